@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,6 +13,7 @@ type Config struct {
 	Server   ServerConfig   `yaml:"server"`
 	Database DatabaseConfig `yaml:"database"`
 	Cron     CronConfig     `yaml:"cron"`
+	Fetch    FetchConfig    `yaml:"fetch"`
 }
 
 type ServerConfig struct {
@@ -28,6 +30,12 @@ type CronConfig struct {
 	ProcessInterval string `yaml:"process_interval"` // 文章处理间隔
 }
 
+type FetchConfig struct {
+	Workers int           `yaml:"workers"`  // 并发抓取数
+	PerHost int           `yaml:"per_host"` // 同一域名最大并发数
+	Timeout time.Duration `yaml:"timeout"`  // 单个Feed抓取超时
+}
+
 // Load 加载配置文件
 func Load(configPath string) (*Config, error) {
 	// 默认配置
@@ -43,6 +51,11 @@ func Load(configPath string) (*Config, error) {
 			FetchInterval:   "*/30 * * * *", // 每30分钟
 			ProcessInterval: "*/10 * * * *", // 每10分钟
 		},
+		Fetch: FetchConfig{
+			Workers: 8,
+			PerHost: 2,
+			Timeout: 60 * time.Second,
+		},
 	}
 
 	// 如果配置文件存在,读取配置
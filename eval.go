@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"gorm.io/gorm"
+
+	"go-news/internal/service"
+)
+
+// runEval 子命令: 用指定的筛选提示词版本和模型评估黄金集并输出报告
+//
+//	go-news eval [-prompt 版本ID] [-model 模型名]
+func runEval(db *gorm.DB, args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	promptID := fs.Uint("prompt", 0, "提示词版本ID,默认使用当前的全局筛选提示词")
+	modelName := fs.String("model", "", "模型名,默认使用设置中的模型")
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	processor := service.NewProcessorService(db, service.NewLLMService(db))
+	evaluations := service.NewEvaluationService(db, processor)
+
+	evaluation, err := evaluations.Create(uint(*promptID), *modelName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "评估失败:", err)
+		return 1
+	}
+	runErr := evaluations.Run(ctx, evaluation)
+
+	v := evaluation.PromptVersion
+	fmt.Printf("\n评估 #%d: %s v%d (%s), 模型 %s\n", evaluation.ID, v.Key, v.Version, v.Scope, evaluation.Model)
+	fmt.Printf("黄金集 %d 篇, 调用失败 %d 篇\n", evaluation.Total, evaluation.Failed)
+	fmt.Printf("TP %d  FP %d  TN %d  FN %d\n",
+		evaluation.TruePositive, evaluation.FalsePositive, evaluation.TrueNegative, evaluation.FalseNegative)
+	fmt.Printf("精确率 %.3f  召回率 %.3f  一致率 %.3f  相关度误差 %.1f\n\n",
+		evaluation.Precision, evaluation.Recall, evaluation.Agreement, evaluation.ScoreError)
+
+	for _, item := range evaluation.Results {
+		title := ""
+		if item.Article != nil {
+			title = item.Article.Title
+		}
+		switch {
+		case item.Error != "":
+			fmt.Printf("  ✗ #%d %s\n      错误: %s\n", item.ArticleID, title, item.Error)
+		case item.Worth != item.ExpectedWorth:
+			fmt.Printf("  ≠ #%d %s\n      期望 %s, 实际 %s (%d): %s\n", item.ArticleID, title,
+				worthLabel(item.ExpectedWorth), worthLabel(item.Worth), item.Score, item.Reason)
+		}
+	}
+
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, "评估中断:", runErr)
+		return 1
+	}
+	return 0
+}
+
+func worthLabel(worth bool) string {
+	if worth {
+		return "值得阅读"
+	}
+	return "不值得"
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,25 +14,45 @@ import (
 )
 
 type Handler struct {
-	db        *gorm.DB
-	feed      *service.FeedService
-	llm       *service.LLMService
-	processor *service.ProcessorService
-	status    *service.StatusService
-	scheduler interface {
+	db          *gorm.DB
+	feed        *service.FeedService
+	opml        *service.OPMLService
+	output      *service.OutputService
+	llm         *service.LLMService
+	processor   *service.ProcessorService
+	status      *service.StatusService
+	usage       *service.UsageService
+	calls       *service.CallLogService
+	tags        *service.TagService
+	prompts     *service.PromptService
+	experiments *service.ExperimentService
+	evaluations *service.EvaluationService
+	feedback    *service.FeedbackService
+	entities    *service.EntityService
+	scheduler   interface {
 		GetNextFetchTime() time.Time
 		GetNextProcessTime() time.Time
 	}
 }
 
-func NewHandler(db *gorm.DB) *Handler {
-	llm := service.NewLLMService(db)
+// NewHandler 创建处理器,服务实例与调度器共享
+func NewHandler(db *gorm.DB, feed *service.FeedService, llm *service.LLMService, processor *service.ProcessorService) *Handler {
 	return &Handler{
-		db:        db,
-		feed:      service.NewFeedService(db),
-		llm:       llm,
-		processor: service.NewProcessorService(db, llm),
-		status:    service.NewStatusService(db),
+		db:          db,
+		feed:        feed,
+		opml:        service.NewOPMLService(db),
+		output:      service.NewOutputService(db),
+		llm:         llm,
+		processor:   processor,
+		status:      service.NewStatusService(db),
+		usage:       service.NewUsageService(db),
+		calls:       service.NewCallLogService(db),
+		tags:        service.NewTagService(db),
+		prompts:     service.NewPromptService(db),
+		experiments: service.NewExperimentService(db, processor),
+		evaluations: service.NewEvaluationService(db, processor),
+		feedback:    service.NewFeedbackService(db),
+		entities:    service.NewEntityService(db),
 	}
 }
 
@@ -50,6 +71,18 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	r.GET("/articles", h.ArticlesPage)
 	r.GET("/settings", h.SettingsPage)
 	r.GET("/status", h.StatusPage)
+	r.GET("/llm-calls", h.LLMCallsPage)
+	r.GET("/prompts", h.PromptsPage)
+	r.GET("/evaluations", h.EvaluationsPage)
+	r.GET("/entities", h.EntitiesPage)
+
+	// 输出已处理文章,供其他阅读器订阅
+	output := r.Group("/output")
+	{
+		output.GET("/rss.xml", h.OutputRSS)
+		output.GET("/atom.xml", h.OutputAtom)
+		output.GET("/feed.json", h.OutputJSONFeed)
+	}
 
 	// API
 	api := r.Group("/api")
@@ -57,20 +90,64 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 		// Feeds
 		api.GET("/feeds", h.ListFeeds)
 		api.POST("/feeds", h.CreateFeed)
+		api.POST("/feeds/fetch", h.FetchAllFeeds)
+		api.POST("/feeds/discover", h.DiscoverFeeds)
+		api.POST("/feeds/import", h.ImportOPML)
+		api.GET("/feeds/export", h.ExportOPML)
+		api.PATCH("/feeds/:id", h.UpdateFeed)
 		api.DELETE("/feeds/:id", h.DeleteFeed)
 		api.POST("/feeds/:id/fetch", h.FetchFeed)
 
+		// Folders
+		api.GET("/folders/prompts", h.ListFolderPrompts)
+		api.PUT("/folders/prompts", h.SaveFolderPrompt)
+
 		// Articles
 		api.GET("/articles", h.ListArticles)
 		api.POST("/articles/process", h.ProcessArticles)
+		api.POST("/articles/retry", h.RetryFailedArticles)
+		api.POST("/articles/:id/retry", h.RetryArticle)
+		api.GET("/articles/:id/revisions", h.ListArticleRevisions)
+		api.GET("/articles/:id/llm-calls", h.ListArticleLLMCalls)
+		api.PUT("/articles/:id/vote", h.VoteArticle)
+		api.DELETE("/articles/:id/vote", h.UnvoteArticle)
+		api.GET("/feedback/report", h.GetFeedbackReport)
+
+		// Stories
+		api.GET("/stories/:id", h.GetStory)
+
+		// Tags
+		api.GET("/tags", h.ListTags)
+
+		// Entities
+		api.GET("/entities", h.ListEntities)
+		api.GET("/entities/:id", h.GetEntity)
 
 		// Config
 		api.GET("/config", h.GetConfig)
 		api.POST("/config", h.SaveConfig)
 
+		// Prompts
+		api.GET("/prompts/versions", h.ListPromptVersions)
+		api.POST("/prompts/versions/:id/restore", h.RestorePromptVersion)
+		api.GET("/prompts/experiments", h.ListPromptExperiments)
+		api.POST("/prompts/experiments", h.StartPromptExperiment)
+		api.GET("/prompts/experiments/:id", h.GetPromptExperiment)
+
+		// Golden set & evaluations
+		api.GET("/golden", h.ListGoldenLabels)
+		api.PUT("/golden/:article_id", h.SaveGoldenLabel)
+		api.DELETE("/golden/:article_id", h.DeleteGoldenLabel)
+		api.GET("/evaluations", h.ListEvaluations)
+		api.POST("/evaluations", h.StartEvaluation)
+		api.GET("/evaluations/:id", h.GetEvaluation)
+
 		// LLM
 		api.GET("/llm/models", h.GetLLMModels)
 		api.POST("/llm/test", h.TestLLMConnection)
+		api.GET("/llm/usage", h.GetLLMUsage)
+		api.GET("/llm/calls", h.ListLLMCalls)
+		api.GET("/llm/calls/:id", h.GetLLMCall)
 
 		// Status
 		api.GET("/status", h.GetStatus)
@@ -92,6 +169,20 @@ func (h *Handler) CreateFeed(c *gin.Context) {
 		return
 	}
 
+	// 未填写名称时使用Feed标题
+	if strings.TrimSpace(feed.Name) == "" {
+		feeds, err := h.feed.Discover(c.Request.Context(), feed.URL)
+		if err != nil || len(feeds) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未能识别订阅源,请使用发现功能或填写名称"})
+			return
+		}
+		feed.URL = feeds[0].URL
+		feed.Name = feeds[0].Title
+		if feed.Name == "" {
+			feed.Name = feed.URL
+		}
+	}
+
 	if err := h.db.Create(&feed).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -100,6 +191,103 @@ func (h *Handler) CreateFeed(c *gin.Context) {
 	c.JSON(http.StatusOK, feed)
 }
 
+func (h *Handler) UpdateFeed(c *gin.Context) {
+	var feed model.Feed
+	if err := h.db.First(&feed, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "feed not found"})
+		return
+	}
+
+	var input struct {
+		Name          *string `json:"name"`
+		Folder        *string `json:"folder"`
+		Enabled       *bool   `json:"enabled"`
+		FetchFullText *bool   `json:"fetch_full_text"`
+		PromptFilter  *string `json:"prompt_filter"`
+		PromptSummary *string `json:"prompt_summary"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if input.Name != nil {
+		updates["name"] = *input.Name
+	}
+	if input.Folder != nil {
+		updates["folder"] = *input.Folder
+	}
+	if input.FetchFullText != nil {
+		updates["fetch_full_text"] = *input.FetchFullText
+	}
+	for column, prompt := range map[string]*string{"prompt_filter": input.PromptFilter, "prompt_summary": input.PromptSummary} {
+		if prompt == nil {
+			continue
+		}
+		if err := service.ValidatePrompt(*prompt); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates[column] = *prompt
+	}
+	if input.Enabled != nil {
+		updates["enabled"] = *input.Enabled
+		// 重新启用时清除失败记录,立即参与下次抓取
+		if *input.Enabled {
+			updates["failure_count"] = 0
+			updates["next_fetch_at"] = nil
+		}
+	}
+
+	if err := h.db.Model(&feed).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, key := range []string{model.ConfigPromptFilter, model.ConfigPromptSummary} {
+		if prompt, ok := updates[key].(string); ok {
+			if _, err := h.prompts.Record(key, model.FeedPromptScope(feed.ID), prompt); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
+
+// ListFolderPrompts 文件夹的提示词覆盖
+func (h *Handler) ListFolderPrompts(c *gin.Context) {
+	folders, err := h.prompts.ListFolders()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"folders": folders})
+}
+
+// SaveFolderPrompt 设置文件夹的提示词覆盖,两个提示词都为空时清除
+func (h *Handler) SaveFolderPrompt(c *gin.Context) {
+	var input struct {
+		Folder        string `json:"folder" binding:"required"`
+		PromptFilter  string `json:"prompt_filter"`
+		PromptSummary string `json:"prompt_summary"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.prompts.SaveFolder(input.Folder, input.PromptFilter, input.PromptSummary); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "saved"})
+}
+
 func (h *Handler) DeleteFeed(c *gin.Context) {
 	id := c.Param("id")
 	h.db.Delete(&model.Feed{}, id)
@@ -123,14 +311,88 @@ func (h *Handler) FetchFeed(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"new_articles": count})
 }
 
+func (h *Handler) FetchAllFeeds(c *gin.Context) {
+	summary, err := h.feed.FetchAllFeeds(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func (h *Handler) DiscoverFeeds(c *gin.Context) {
+	var input struct {
+		URL string `json:"url" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	feeds, err := h.feed.Discover(c.Request.Context(), input.URL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"feeds": feeds})
+}
+
+func (h *Handler) ImportOPML(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传OPML文件"})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	results, err := h.opml.Import(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created := 0
+	for _, r := range results {
+		if r.Status == service.OPMLImportCreated {
+			created++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"created": created,
+		"total":   len(results),
+		"results": results,
+	})
+}
+
+func (h *Handler) ExportOPML(c *gin.Context) {
+	data, err := h.opml.Export()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="go-news.opml"`)
+	c.Data(http.StatusOK, "text/x-opml; charset=utf-8", data)
+}
+
 // ===== Article相关 =====
 
 func (h *Handler) ListArticles(c *gin.Context) {
-	status := c.Query("status") // pending, processed, filtered
+	status := c.Query("status") // pending, processed, filtered, duplicate, failed
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize := 20
 
-	query := h.db.Model(&model.Article{}).Preload("Feed")
+	query := h.db.Model(&model.Article{}).Preload("Feed").Preload("Story").Preload("Tags").Preload("Entities").
+		Preload("FilterPrompt", selectPromptVersion).Preload("SummaryPrompt", selectPromptVersion).Preload("Golden").Preload("Vote")
 
 	switch status {
 	case "pending":
@@ -139,13 +401,34 @@ func (h *Handler) ListArticles(c *gin.Context) {
 		query = query.Where("status = ?", model.StatusProcessed)
 	case "filtered":
 		query = query.Where("status = ?", model.StatusFiltered)
+	case "duplicate":
+		query = query.Where("status = ?", model.StatusDuplicate)
+	case "failed":
+		query = query.Where("status = ?", model.StatusFailed)
+	}
+
+	if tag := c.Query("tag"); tag != "" {
+		query = service.WhereTag(query, tag)
+	}
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if entity := c.Query("entity"); entity != "" {
+		query = service.WhereEntity(query, entity)
+	}
+
+	order := "pub_date DESC"
+	if c.Query("sort") == "score" {
+		order = "score DESC, pub_date DESC"
 	}
 
 	var total int64
 	query.Count(&total)
 
+	// 列表不需要原文正文
 	var articles []model.Article
-	query.Order("pub_date DESC").
+	query.Omit("full_html", "full_text").
+		Order(order).
 		Offset((page - 1) * pageSize).
 		Limit(pageSize).
 		Find(&articles)
@@ -157,12 +440,255 @@ func (h *Handler) ListArticles(c *gin.Context) {
 	})
 }
 
+func (h *Handler) RetryArticle(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	count, err := h.processor.RetryArticles([]uint{uint(id)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "failed article not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"retried": count})
+}
+
+func (h *Handler) RetryFailedArticles(c *gin.Context) {
+	count, err := h.processor.RetryArticles(nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"retried": count})
+}
+
+// ListArticleRevisions 获取文章的历史版本,以及每个版本与其下一版本的差异
+func (h *Handler) ListArticleRevisions(c *gin.Context) {
+	var article model.Article
+	if err := h.db.First(&article, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "article not found"})
+		return
+	}
+
+	var revisions []model.ArticleRevision
+	h.db.Where("article_id = ?", article.ID).Order("id").Find(&revisions)
+
+	type revisionDiff struct {
+		Revision    model.ArticleRevision `json:"revision"`
+		TitleDiff   []service.DiffOp      `json:"title_diff"`
+		ContentDiff []service.DiffOp      `json:"content_diff"`
+	}
+
+	result := make([]revisionDiff, 0, len(revisions))
+	for i, rev := range revisions {
+		nextTitle, nextText := article.Title, article.BodyText()
+		if i+1 < len(revisions) {
+			next := revisions[i+1]
+			nextTitle, nextText = next.Title, revisionText(next)
+		}
+		result = append(result, revisionDiff{
+			Revision:    rev,
+			TitleDiff:   service.DiffText(rev.Title, nextTitle),
+			ContentDiff: service.DiffText(service.PlainText(revisionText(rev)), service.PlainText(nextText)),
+		})
+	}
+
+	// 最新的修改排在前面
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"article":   article,
+		"revisions": result,
+	})
+}
+
+func revisionText(rev model.ArticleRevision) string {
+	if rev.FullText != "" {
+		return rev.FullText
+	}
+	return rev.Content
+}
+
+// GetStory 获取同一事件的所有报道
+func (h *Handler) GetStory(c *gin.Context) {
+	var story model.Story
+	if err := h.db.First(&story, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "story not found"})
+		return
+	}
+
+	var articles []model.Article
+	h.db.Preload("Feed").Where("story_id = ?", story.ID).Order("pub_date").Find(&articles)
+
+	c.JSON(http.StatusOK, gin.H{
+		"story":    story,
+		"articles": articles,
+	})
+}
+
+// ListArticleLLMCalls 获取文章的全部LLM调用,包含完整的提示词、输入和响应
+func (h *Handler) ListArticleLLMCalls(c *gin.Context) {
+	var article model.Article
+	if err := h.db.Preload("Feed").First(&article, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "article not found"})
+		return
+	}
+
+	calls, err := h.calls.ForArticle(article.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"article": article,
+		"calls":   calls,
+	})
+}
+
+// VoteArticle 用户反馈: vote 为 1 (值得阅读) 或 -1 (不值得阅读)
+func (h *Handler) VoteArticle(c *gin.Context) {
+	var input struct {
+		Vote int `json:"vote" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	vote, err := h.feedback.Vote(uint(id), input.Vote)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, vote)
+}
+
+func (h *Handler) UnvoteArticle(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := h.feedback.Unvote(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// GetFeedbackReport LLM 筛选结论与用户反馈的分歧统计
+func (h *Handler) GetFeedbackReport(c *gin.Context) {
+	report, err := h.feedback.Report()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListTags 已处理文章中最常用的标签
+func (h *Handler) ListTags(c *gin.Context) {
+	tags, err := h.tags.Popular(100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
 func (h *Handler) ProcessArticles(c *gin.Context) {
+	if err := h.usage.CheckBudget(); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 使用独立的 context,不受 HTTP 请求生命周期影响
 	go h.processor.ProcessPendingArticles(context.Background(), 10)
 	c.JSON(http.StatusOK, gin.H{"message": "processing started"})
 }
 
+// ===== 输出Feed =====
+
+func (h *Handler) OutputRSS(c *gin.Context) {
+	h.renderOutput(c, "application/rss+xml; charset=utf-8", h.output.RenderRSS)
+}
+
+func (h *Handler) OutputAtom(c *gin.Context) {
+	h.renderOutput(c, "application/atom+xml; charset=utf-8", h.output.RenderAtom)
+}
+
+func (h *Handler) OutputJSONFeed(c *gin.Context) {
+	h.renderOutput(c, "application/feed+json; charset=utf-8", h.output.RenderJSONFeed)
+}
+
+func (h *Handler) renderOutput(c *gin.Context, contentType string,
+	render func(service.OutputMeta, []model.Article) ([]byte, error)) {
+	feedID, _ := strconv.Atoi(c.Query("feed_id"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	filter := service.OutputFilter{
+		FeedID: uint(feedID),
+		Folder: c.Query("folder"),
+		Tag:    c.Query("tag"),
+		Limit:  limit,
+	}
+
+	articles, err := h.output.Articles(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	title := "go-news"
+	if filter.FeedID > 0 {
+		var feed model.Feed
+		if h.db.First(&feed, filter.FeedID).Error == nil {
+			title += " - " + feed.Name
+		}
+	}
+	if filter.Folder != "" {
+		title += " - " + filter.Folder
+	}
+	if filter.Tag != "" {
+		title += " - #" + filter.Tag
+	}
+
+	base := requestBaseURL(c)
+	data, err := render(service.OutputMeta{
+		Title:   title,
+		HomeURL: base + "/articles",
+		SelfURL: base + c.Request.URL.RequestURI(),
+	}, articles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// requestBaseURL 根据请求(含反向代理头)推断站点地址
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host
+}
+
 // ===== Config相关 =====
 
 func (h *Handler) GetConfig(c *gin.Context) {
@@ -184,8 +710,23 @@ func (h *Handler) SaveConfig(c *gin.Context) {
 		return
 	}
 
+	for key, value := range input {
+		if strings.HasPrefix(key, "prompt_") {
+			if err := service.ValidatePrompt(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": key + ": " + err.Error()})
+				return
+			}
+		}
+	}
+
 	for key, value := range input {
 		h.db.Where("key = ?", key).Assign(model.Config{Value: value}).FirstOrCreate(&model.Config{Key: key})
+		if strings.HasPrefix(key, "prompt_") {
+			if _, err := h.prompts.Record(key, model.PromptScopeGlobal, value); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "saved"})
@@ -199,8 +740,9 @@ func (h *Handler) IndexPage(c *gin.Context) {
 
 func (h *Handler) FeedsPage(c *gin.Context) {
 	var feeds []model.Feed
-	h.db.Find(&feeds)
-	c.HTML(http.StatusOK, "feeds.html", gin.H{"feeds": feeds})
+	h.db.Order("folder, name").Find(&feeds)
+	folderPrompts, _ := h.prompts.ListFolders()
+	c.HTML(http.StatusOK, "feeds.html", gin.H{"feeds": feeds, "folderPrompts": folderPrompts})
 }
 
 func (h *Handler) ArticlesPage(c *gin.Context) {
@@ -249,6 +791,267 @@ func (h *Handler) TestLLMConnection(c *gin.Context) {
 	})
 }
 
+// GetLLMUsage 获取每日/每月token用量和费用
+func (h *Handler) GetLLMUsage(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days <= 0 || days > 366 {
+		days = 30
+	}
+	months, _ := strconv.Atoi(c.DefaultQuery("months", "12"))
+	if months <= 0 || months > 36 {
+		months = 12
+	}
+
+	report, err := h.usage.Report(days, months)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListLLMCalls 分页查询LLM调用记录,支持按文章、阶段、状态和模型筛选
+func (h *Handler) ListLLMCalls(c *gin.Context) {
+	articleID, _ := strconv.Atoi(c.Query("article_id"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+
+	calls, total, err := h.calls.List(service.LLMCallFilter{
+		ArticleID: uint(articleID),
+		Stage:     c.Query("stage"),
+		Status:    c.Query("status"),
+		Model:     c.Query("model"),
+		Page:      page,
+		PageSize:  pageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  calls,
+		"total": total,
+		"page":  page,
+	})
+}
+
+func (h *Handler) GetLLMCall(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	call, err := h.calls.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "call not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, call)
+}
+
+func (h *Handler) LLMCallsPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "llm_calls.html", gin.H{"article_id": c.Query("article_id")})
+}
+
+// ===== 提示词版本 =====
+
+// selectPromptVersion 文章列表只需要版本号,不加载提示词内容
+func selectPromptVersion(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "key", "scope", "version", "created_at")
+}
+
+func (h *Handler) PromptsPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "prompts.html", nil)
+}
+
+// ListPromptVersions 提示词历史版本 (key, scope)
+func (h *Handler) ListPromptVersions(c *gin.Context) {
+	versions, err := h.prompts.Versions(c.Query("key"), c.Query("scope"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// RestorePromptVersion 恢复到指定版本
+func (h *Handler) RestorePromptVersion(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	version, err := h.prompts.Restore(uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+func (h *Handler) ListPromptExperiments(c *gin.Context) {
+	experiments, err := h.experiments.List(50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"experiments": experiments})
+}
+
+// StartPromptExperiment 用两个版本处理同一批样本文章,在后台运行
+func (h *Handler) StartPromptExperiment(c *gin.Context) {
+	var input struct {
+		VersionA   uint `json:"version_a" binding:"required"`
+		VersionB   uint `json:"version_b" binding:"required"`
+		SampleSize int  `json:"sample_size"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	experiment, err := h.experiments.Start(input.VersionA, input.VersionB, input.SampleSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, experiment)
+}
+
+func (h *Handler) GetPromptExperiment(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	experiment, err := h.experiments.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "experiment not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, experiment)
+}
+
+// ===== 实体 =====
+
+func (h *Handler) EntitiesPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "entities.html", nil)
+}
+
+// ListEntities 实体列表 (type, q 按名称搜索)
+func (h *Handler) ListEntities(c *gin.Context) {
+	entities, err := h.entities.Index(c.Query("type"), c.Query("q"), 200)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entities": entities})
+}
+
+// GetEntity 实体及最近每天提及它的文章数
+func (h *Handler) GetEntity(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	entity, err := h.entities.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "entity not found"})
+		return
+	}
+
+	timeline, err := h.entities.Timeline(entity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entity": entity, "timeline": timeline})
+}
+
+// ===== 黄金集与评估 =====
+
+func (h *Handler) EvaluationsPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "evaluations.html", nil)
+}
+
+func (h *Handler) ListGoldenLabels(c *gin.Context) {
+	labels, err := h.evaluations.Labels()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"labels": labels})
+}
+
+// SaveGoldenLabel 标注文章的期望筛选结论 (worth, score 可选, note)
+func (h *Handler) SaveGoldenLabel(c *gin.Context) {
+	var input struct {
+		Worth *bool  `json:"worth" binding:"required"`
+		Score *int   `json:"score"`
+		Note  string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	articleID, _ := strconv.Atoi(c.Param("article_id"))
+	label, err := h.evaluations.Label(uint(articleID), *input.Worth, input.Score, input.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+func (h *Handler) DeleteGoldenLabel(c *gin.Context) {
+	articleID, _ := strconv.Atoi(c.Param("article_id"))
+	if err := h.evaluations.Unlabel(uint(articleID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+func (h *Handler) ListEvaluations(c *gin.Context) {
+	evaluations, err := h.evaluations.List(50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"evaluations": evaluations})
+}
+
+// StartEvaluation 用指定的提示词版本和模型评估黄金集,在后台运行
+func (h *Handler) StartEvaluation(c *gin.Context) {
+	var input struct {
+		PromptVersionID uint   `json:"prompt_version_id"`
+		Model           string `json:"model"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	evaluation, err := h.evaluations.Start(input.PromptVersionID, strings.TrimSpace(input.Model))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, evaluation)
+}
+
+func (h *Handler) GetEvaluation(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	evaluation, err := h.evaluations.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "evaluation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, evaluation)
+}
+
 // ===== Status相关 =====
 
 func (h *Handler) StatusPage(c *gin.Context) {
@@ -262,6 +1065,8 @@ func (h *Handler) GetStatus(c *gin.Context) {
 		return
 	}
 
+	status.LastFetch = h.feed.LastFetchSummary()
+
 	// 添加定时任务信息
 	if h.scheduler != nil {
 		status.NextFetchTime = h.scheduler.GetNextFetchTime()
@@ -8,18 +8,62 @@ const (
 	StatusPending   ArticleStatus = 0 // 未处理
 	StatusProcessed ArticleStatus = 1 // 已处理
 	StatusFiltered  ArticleStatus = 2 // 已过滤(不重要)
+	StatusDuplicate ArticleStatus = 3 // 重复报道(同一Story的非代表文章)
+	StatusFailed    ArticleStatus = 4 // 处理失败(超过最大重试次数)
 )
 
 type Article struct {
-	ID          uint          `gorm:"primaryKey" json:"id"`
-	FeedID      uint          `gorm:"not null" json:"feed_id"`
-	Feed        Feed          `gorm:"foreignKey:FeedID" json:"feed,omitempty"`
-	Title       string        `gorm:"size:500;not null" json:"title"`
-	Link        string        `gorm:"size:500;uniqueIndex;not null" json:"link"`
-	Content     string        `gorm:"type:text" json:"content"`
-	PubDate     time.Time     `json:"pub_date"`
-	Status      ArticleStatus `gorm:"default:0" json:"status"`
-	Summary     string        `gorm:"type:text" json:"summary"`
-	ProcessedAt *time.Time    `json:"processed_at,omitempty"`
-	CreatedAt   time.Time     `json:"created_at"`
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	FeedID       uint          `gorm:"not null;index:idx_article_feed_guid" json:"feed_id"`
+	Feed         Feed          `gorm:"foreignKey:FeedID" json:"feed,omitempty"`
+	Title        string        `gorm:"size:500;not null" json:"title"`
+	GUID         string        `gorm:"column:guid;size:500;index:idx_article_feed_guid" json:"guid"`
+	Link         string        `gorm:"size:500;uniqueIndex;not null" json:"link"`
+	CanonicalURL string        `gorm:"size:500;index" json:"canonical_url"`
+	Content      string        `gorm:"type:text" json:"content"`
+	FullHTML     string        `gorm:"type:text" json:"-"` // 原文正文可达数十KB,只在服务端使用
+	FullText     string        `gorm:"type:text" json:"-"`
+	PubDate      time.Time     `json:"pub_date"`
+	Status       ArticleStatus `gorm:"default:0" json:"status"`
+	Summary      string        `gorm:"type:text" json:"summary"`
+	Score        int           `gorm:"index" json:"score"` // 相关度 0-100
+	Category     string        `gorm:"size:50;index" json:"category"`
+	Tags         []Tag         `gorm:"many2many:article_tags" json:"tags,omitempty"`
+	Entities     []Entity      `gorm:"many2many:article_entities" json:"entities,omitempty"`
+	ProcessedAt  *time.Time    `json:"processed_at,omitempty"`
+	Attempts     int           `gorm:"default:0" json:"attempts"`
+	LastError    string        `gorm:"type:text" json:"last_error,omitempty"`
+	NextRetryAt  *time.Time    `gorm:"index" json:"next_retry_at,omitempty"`
+	SimHash      int64         `gorm:"index" json:"-"`
+	StoryID      *uint         `gorm:"index" json:"story_id,omitempty"`
+	Story        *Story        `gorm:"foreignKey:StoryID" json:"story,omitempty"`
+
+	// 处理时使用的提示词版本和模型
+	FilterPromptID  *uint          `gorm:"index" json:"filter_prompt_id,omitempty"`
+	FilterPrompt    *PromptVersion `gorm:"foreignKey:FilterPromptID" json:"filter_prompt,omitempty"`
+	SummaryPromptID *uint          `gorm:"index" json:"summary_prompt_id,omitempty"`
+	SummaryPrompt   *PromptVersion `gorm:"foreignKey:SummaryPromptID" json:"summary_prompt,omitempty"`
+	ProcessedModel  string         `gorm:"size:100" json:"processed_model,omitempty"`
+
+	// 检测到的语言代码,以及翻译为目标语言的标题 (与目标语言相同时为空)
+	Language        string `gorm:"size:35;index" json:"language,omitempty"`
+	TranslatedTitle string `gorm:"size:500" json:"translated_title,omitempty"`
+
+	// 黄金集标注,未标注时为空
+	Golden *GoldenLabel `gorm:"foreignKey:ArticleID" json:"golden,omitempty"`
+	// 用户反馈,未投票时为空
+	Vote *ArticleVote `gorm:"foreignKey:ArticleID" json:"vote,omitempty"`
+
+	// 内容更新记录,历史版本保存在 article_revisions
+	RevisionCount    int        `gorm:"default:0" json:"revision_count"`
+	ContentUpdatedAt *time.Time `json:"content_updated_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// BodyText 返回用于处理的正文,优先使用提取的全文
+func (a *Article) BodyText() string {
+	if a.FullText != "" {
+		return a.FullText
+	}
+	return a.Content
 }
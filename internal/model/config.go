@@ -17,4 +17,41 @@ const (
 	ConfigLLMModel      = "llm_model"
 	ConfigPromptFilter  = "prompt_filter"
 	ConfigPromptSummary = "prompt_summary"
+
+	ConfigReprocessOnUpdate = "reprocess_on_update" // 文章内容更新后重新交给LLM处理
+	ConfigScoreThreshold    = "score_threshold"     // 相关度低于该值的文章标记为已过滤,0为只看筛选结论
+	ConfigProcessMode       = "process_mode"        // 处理模式: two_step 或 combined
+	ConfigPromptCombined    = "prompt_combined"     // 合并模式下一次完成筛选和摘要的提示词
+
+	// LLM请求控制
+	ConfigLLMTimeout           = "llm_timeout"             // 单次请求超时(秒)
+	ConfigLLMMaxRetries        = "llm_max_retries"         // 限流、超时和服务端错误的最大重试次数
+	ConfigLLMRequestsPerMinute = "llm_requests_per_minute" // 每分钟请求数上限,0为不限制
+	ConfigLLMTokensPerMinute   = "llm_tokens_per_minute"   // 每分钟token数上限,0为不限制
+	ConfigLLMStructuredOutput  = "llm_structured_output"   // 筛选时使用提供商原生的结构化输出(JSON Schema)
+
+	// 长文章处理
+	ConfigLLMModelLimits     = "llm_model_limits"     // 每行: 模型名 上下文窗口(token) [每token字符数]
+	ConfigFilterTruncation   = "filter_truncation"    // 筛选时超出上下文的截断方式: head 或 head_tail
+	ConfigPromptChunkSummary = "prompt_chunk_summary" // 长文章分段摘要的提示词
+
+	// 翻译
+	ConfigTargetLanguage  = "target_language"  // 目标语言代码,提示词中可用 {{.TargetLanguage}} 引用
+	ConfigTranslateTitle  = "translate_title"  // 为其他语言的文章翻译标题
+	ConfigPromptTranslate = "prompt_translate" // 标题翻译提示词
+
+	// 实体提取
+	ConfigEntityExtraction = "entity_extraction" // 提取方式: off、rules 或 llm
+	ConfigPromptEntities   = "prompt_entities"   // LLM 提取实体的提示词
+
+	// 用户反馈
+	ConfigFewShotCount = "few_shot_count" // 筛选时附带的用户反馈示例数,0为不使用
+
+	// 费用统计
+	ConfigLLMPrices        = "llm_prices"         // 每行: 模型名 输入价格 输出价格 (美元/百万token)
+	ConfigLLMMonthlyBudget = "llm_monthly_budget" // 每月预算(美元),超出后暂停处理,0为不限制
+
+	// LLM调用记录保留策略
+	ConfigLLMCallRetentionDays = "llm_call_retention_days" // 保留天数,0为不按时间清理
+	ConfigLLMCallMaxRecords    = "llm_call_max_records"    // 最多保留条数,0为不限制
 )
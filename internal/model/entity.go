@@ -0,0 +1,23 @@
+package model
+
+// 实体类型
+const (
+	EntityCompany = "company" // 公司和组织
+	EntityProduct = "product" // 产品、项目和服务
+	EntityPerson  = "person"  // 人物
+	EntityCVE     = "cve"     // 漏洞编号
+	EntityVersion = "version" // 带版本号的产品,如 Go 1.25
+	EntityKeyword = "keyword" // 其他关键词
+)
+
+// EntityTypes 全部实体类型
+var EntityTypes = []string{EntityCompany, EntityProduct, EntityPerson, EntityCVE, EntityVersion, EntityKeyword}
+
+// Entity 从文章中提取的命名实体,通过 article_entities 关联文章。
+// 同一类型下按 Key (小写) 去重,Name 保留首次出现时的写法
+type Entity struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Type string `gorm:"size:20;uniqueIndex:idx_entity_type_key;not null" json:"type"`
+	Key  string `gorm:"size:100;uniqueIndex:idx_entity_type_key;not null" json:"-"`
+	Name string `gorm:"size:100;not null" json:"name"`
+}
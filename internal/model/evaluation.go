@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// GoldenLabel 人工标注的筛选结论,组成评估提示词和模型用的黄金集
+type GoldenLabel struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ArticleID uint      `gorm:"uniqueIndex;not null" json:"article_id"`
+	Article   *Article  `gorm:"foreignKey:ArticleID" json:"article,omitempty"`
+	Worth     bool      `json:"worth"`
+	Score     *int      `json:"score,omitempty"` // 期望的相关度,可不填
+	Note      string    `gorm:"type:text" json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Evaluation 用指定的提示词版本和模型处理黄金集,统计与标注的一致程度。
+// 以"值得阅读"为正类计算精确率和召回率
+type Evaluation struct {
+	ID              uint             `gorm:"primaryKey" json:"id"`
+	PromptVersionID uint             `json:"prompt_version_id"`
+	PromptVersion   *PromptVersion   `gorm:"foreignKey:PromptVersionID" json:"prompt_version,omitempty"`
+	Model           string           `gorm:"size:100" json:"model"`
+	Status          string           `gorm:"size:20" json:"status"` // 与对比实验相同: running/done/failed
+	Error           string           `gorm:"type:text" json:"error,omitempty"`
+	Total           int              `json:"total"`
+	TruePositive    int              `json:"true_positive"`
+	FalsePositive   int              `json:"false_positive"`
+	TrueNegative    int              `json:"true_negative"`
+	FalseNegative   int              `json:"false_negative"`
+	Failed          int              `json:"failed"` // 调用失败,不计入指标
+	Precision       float64          `json:"precision"`
+	Recall          float64          `json:"recall"`
+	Agreement       float64          `json:"agreement"`
+	ScoreError      float64          `json:"score_error"` // 有期望相关度的文章的平均绝对误差
+	Results         []EvaluationItem `gorm:"foreignKey:EvaluationID;constraint:OnDelete:CASCADE" json:"results,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	FinishedAt      *time.Time       `json:"finished_at,omitempty"`
+}
+
+// EvaluationItem 黄金集中单篇文章的评估结果
+type EvaluationItem struct {
+	ID            uint     `gorm:"primaryKey" json:"id"`
+	EvaluationID  uint     `gorm:"index" json:"evaluation_id"`
+	ArticleID     uint     `json:"article_id"`
+	Article       *Article `gorm:"foreignKey:ArticleID" json:"article,omitempty"`
+	ExpectedWorth bool     `json:"expected_worth"`
+	ExpectedScore *int     `json:"expected_score,omitempty"`
+	Worth         bool     `json:"worth"`
+	Score         int      `json:"score"`
+	Reason        string   `gorm:"type:text" json:"reason"`
+	Error         string   `gorm:"type:text" json:"error,omitempty"`
+}
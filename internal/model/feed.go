@@ -3,10 +3,34 @@ package model
 import "time"
 
 type Feed struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"size:255;not null" json:"name"`
-	URL       string    `gorm:"size:500;uniqueIndex;not null" json:"url"`
-	Enabled   bool      `gorm:"default:true" json:"enabled"`
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	Name    string `gorm:"size:255;not null" json:"name"`
+	URL     string `gorm:"size:500;uniqueIndex;not null" json:"url"`
+	Enabled bool   `gorm:"default:true" json:"enabled"`
+	Folder  string `gorm:"size:255;index" json:"folder"`
+
+	// 抓取文章原网页并提取正文,适用于只提供摘要的Feed
+	FetchFullText bool `gorm:"default:false" json:"fetch_full_text"`
+
+	// Feed 声明的语言,供提示词模板使用
+	Language string `gorm:"size:35" json:"language"`
+
+	// 覆盖文件夹和全局的提示词,为空时不覆盖
+	PromptFilter  string `gorm:"type:text" json:"prompt_filter"`
+	PromptSummary string `gorm:"type:text" json:"prompt_summary"`
+
+	// 条件请求缓存
+	ETag         string `gorm:"column:etag;size:255" json:"etag,omitempty"`
+	LastModified string `gorm:"size:100" json:"last_modified,omitempty"`
+	ContentHash  string `gorm:"size:64" json:"content_hash,omitempty"`
+
+	// 抓取健康状态
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastError     string     `gorm:"type:text" json:"last_error"`
+	FailureCount  int        `gorm:"default:0" json:"failure_count"`
+	NextFetchAt   *time.Time `gorm:"index" json:"next_fetch_at,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// 用户对文章的反馈
+const (
+	VoteUp   = 1  // 值得阅读
+	VoteDown = -1 // 不值得阅读
+)
+
+// ArticleVote 用户对文章的赞或踩,每篇文章一条。
+// 最近的反馈作为示例附加到筛选提示词中,并用于统计 LLM 与用户的分歧
+type ArticleVote struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ArticleID uint      `gorm:"uniqueIndex;not null" json:"article_id"`
+	Article   *Article  `gorm:"foreignKey:ArticleID" json:"article,omitempty"`
+	Vote      int       `json:"vote"` // VoteUp 或 VoteDown
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `gorm:"index" json:"updated_at"`
+}
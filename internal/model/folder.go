@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// FolderPrompt 文件夹级别的提示词覆盖,文件夹内的 Feed 未设置时使用
+type FolderPrompt struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Folder        string    `gorm:"size:255;uniqueIndex;not null" json:"folder"`
+	PromptFilter  string    `gorm:"type:text" json:"prompt_filter"`
+	PromptSummary string    `gorm:"type:text" json:"prompt_summary"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// LLM调用结果
+const (
+	CallStatusSuccess = "success"
+	CallStatusError   = "error"
+)
+
+// LLMCall 单次LLM请求的完整记录,用于排查摘要和筛选结果
+type LLMCall struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ArticleID *uint     `gorm:"index" json:"article_id,omitempty"`
+	Stage     string    `gorm:"size:20;index" json:"stage"`
+	Provider  string    `gorm:"size:50" json:"provider"`
+	Model     string    `gorm:"size:100;index" json:"model"`
+	Attempt   int       `json:"attempt"` // 第几次尝试,从1开始
+	Prompt    string    `gorm:"type:text" json:"prompt,omitempty"`
+	Input     string    `gorm:"type:text" json:"input,omitempty"` // 超长时截断
+	Response  string    `gorm:"type:text" json:"response,omitempty"`
+	LatencyMs int64     `json:"latency_ms"`
+	Status    string    `gorm:"size:20;index" json:"status"`
+	Error     string    `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// 提示词版本的作用范围
+const PromptScopeGlobal = "global"
+
+// FeedPromptScope 订阅源覆盖的提示词范围
+func FeedPromptScope(feedID uint) string {
+	return fmt.Sprintf("feed:%d", feedID)
+}
+
+// FolderPromptScope 文件夹覆盖的提示词范围
+func FolderPromptScope(folder string) string {
+	return "folder:" + folder
+}
+
+// PromptVersion 提示词的历史版本,内容变化时生成新版本
+type PromptVersion struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Key       string    `gorm:"size:50;index:idx_prompt_version_scope" json:"key"`    // 配置键,如 prompt_filter
+	Scope     string    `gorm:"size:300;index:idx_prompt_version_scope" json:"scope"` // global、feed:<id> 或 folder:<名称>
+	Version   int       `json:"version"`                                              // 同一键和范围内从1递增
+	Content   string    `gorm:"type:text" json:"content,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 提示词对比实验状态
+const (
+	ExperimentRunning = "running"
+	ExperimentDone    = "done"
+	ExperimentFailed  = "failed"
+)
+
+// PromptExperiment 用两个提示词版本处理同一批样本文章,对比结果
+type PromptExperiment struct {
+	ID         uint                     `gorm:"primaryKey" json:"id"`
+	Key        string                   `gorm:"size:50" json:"key"`
+	VersionAID uint                     `json:"version_a_id"`
+	VersionA   *PromptVersion           `gorm:"foreignKey:VersionAID" json:"version_a,omitempty"`
+	VersionBID uint                     `json:"version_b_id"`
+	VersionB   *PromptVersion           `gorm:"foreignKey:VersionBID" json:"version_b,omitempty"`
+	SampleSize int                      `json:"sample_size"`
+	Model      string                   `gorm:"size:100" json:"model"`
+	Status     string                   `gorm:"size:20" json:"status"`
+	Error      string                   `gorm:"type:text" json:"error,omitempty"`
+	Results    []PromptExperimentResult `gorm:"foreignKey:ExperimentID;constraint:OnDelete:CASCADE" json:"results,omitempty"`
+	CreatedAt  time.Time                `json:"created_at"`
+	FinishedAt *time.Time               `json:"finished_at,omitempty"`
+}
+
+// PromptExperimentResult 单篇文章在一个版本下的处理结果
+type PromptExperimentResult struct {
+	ID           uint     `gorm:"primaryKey" json:"id"`
+	ExperimentID uint     `gorm:"index" json:"experiment_id"`
+	ArticleID    uint     `json:"article_id"`
+	Article      *Article `gorm:"foreignKey:ArticleID" json:"article,omitempty"`
+	Variant      string   `gorm:"size:1" json:"variant"` // a 或 b
+	Worth        bool     `json:"worth"`
+	Score        int      `json:"score"`
+	Category     string   `gorm:"size:50" json:"category"`
+	Reason       string   `gorm:"type:text" json:"reason"`
+	Summary      string   `gorm:"type:text" json:"summary"`
+	Error        string   `gorm:"type:text" json:"error,omitempty"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// ArticleRevision 文章内容更新前的历史版本
+type ArticleRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ArticleID uint      `gorm:"not null;index" json:"article_id"`
+	Title     string    `gorm:"size:500" json:"title"`
+	Content   string    `gorm:"type:text" json:"content"`
+	FullText  string    `gorm:"type:text" json:"full_text,omitempty"`
+	Summary   string    `gorm:"type:text" json:"summary"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Story 多个订阅源对同一事件的报道
+type Story struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Title            string    `gorm:"size:500" json:"title"`
+	RepresentativeID uint      `gorm:"index" json:"representative_id"` // 代表文章,只有它会交给LLM处理
+	ArticleCount     int       `gorm:"default:1" json:"article_count"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
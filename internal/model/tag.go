@@ -0,0 +1,7 @@
+package model
+
+// Tag 筛选阶段由LLM生成的主题标签,通过 article_tags 关联文章
+type Tag struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"size:50;uniqueIndex;not null" json:"name"`
+}
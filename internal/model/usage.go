@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// LLM调用所属的处理阶段
+const (
+	StageFilter   = "filter"   // 筛选
+	StageSummary  = "summary"  // 摘要
+	StageCombined = "combined" // 合并模式: 一次调用完成筛选和摘要
+
+	StageSummaryChunk = "summary_chunk" // 长文章分段摘要
+	StageExperiment   = "experiment"    // 提示词对比实验
+	StageEvaluation   = "evaluation"    // 黄金集评估
+	StageTranslate    = "translate"     // 标题翻译
+	StageEntities     = "entities"      // 实体提取
+)
+
+// 文章处理模式
+const (
+	ProcessModeTwoStep  = "two_step" // 先筛选,值得阅读再单独生成摘要
+	ProcessModeCombined = "combined" // 一次调用同时返回筛选结果和摘要,输入 token 减半
+)
+
+// 实体提取方式
+const (
+	EntityExtractionOff   = "off"   // 不提取
+	EntityExtractionRules = "rules" // 只按规则提取漏洞编号和版本号
+	EntityExtractionLLM   = "llm"   // 值得阅读的文章再由LLM提取,失败时只使用规则结果
+)
+
+// 筛选阶段超出模型上下文时的截断方式
+const (
+	TruncateHead     = "head"      // 只保留开头
+	TruncateHeadTail = "head_tail" // 保留开头和结尾
+)
+
+// LLMUsage 单次LLM调用的token用量和费用
+type LLMUsage struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	ArticleID        *uint     `gorm:"index" json:"article_id,omitempty"`
+	Stage            string    `gorm:"size:20;index" json:"stage"`
+	Provider         string    `gorm:"size:50" json:"provider"`
+	Model            string    `gorm:"size:100;index" json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Estimated        bool      `json:"estimated"` // 提供商未返回用量,按文本长度估算
+	Cost             float64   `json:"cost"`      // 美元,按调用时的价格计算
+	CreatedAt        time.Time `gorm:"index" json:"created_at"`
+}
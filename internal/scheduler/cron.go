@@ -32,7 +32,13 @@ func (s *Scheduler) Start() {
 	// RSS抓取任务
 	s.fetchEntryID, _ = s.cron.AddFunc(s.config.FetchInterval, func() {
 		log.Println("[Cron] Fetching feeds...")
-		s.feed.FetchAllFeeds(context.Background())
+		summary, err := s.feed.FetchAllFeeds(context.Background())
+		if err != nil {
+			log.Printf("[Cron] Fetch failed: %v", err)
+			return
+		}
+		log.Printf("[Cron] Fetched %d feeds in %s: %d new articles, %d errors",
+			summary.Feeds, summary.Duration.Round(time.Millisecond), summary.NewArticles, summary.Errors)
 	})
 
 	// 文章处理任务
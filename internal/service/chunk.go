@@ -0,0 +1,193 @@
+package service
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go-news/internal/model"
+)
+
+const (
+	defaultCharsPerToken = 4.0
+	// 为模型输出预留的 token 数,不超过上下文的四分之一
+	llmOutputReserve = 1024
+	// 输入预算的下限,避免提示词过长时预算为负
+	minInputBudget = 256
+	// 分段摘要再次合并的最大轮数
+	maxReduceRounds = 3
+)
+
+// ModelLimit 模型的上下文限制和token估算参数
+type ModelLimit struct {
+	ContextTokens int     // 上下文窗口,0为不限制
+	CharsPerToken float64 // 非中日韩文字平均每个token的字符数
+}
+
+// parseModelLimits 解析模型上下文配置,每行: 模型名 上下文窗口 [每token字符数],# 开头为注释
+func parseModelLimits(text string) map[string]ModelLimit {
+	limits := make(map[string]ModelLimit)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			continue
+		}
+		contextTokens, err := strconv.Atoi(fields[1])
+		if err != nil || contextTokens < 0 {
+			continue
+		}
+		limit := ModelLimit{ContextTokens: contextTokens, CharsPerToken: defaultCharsPerToken}
+		if len(fields) == 3 {
+			if chars, err := strconv.ParseFloat(fields[2], 64); err == nil && chars > 0 {
+				limit.CharsPerToken = chars
+			}
+		}
+		limits[strings.ToLower(fields[0])] = limit
+	}
+	return limits
+}
+
+// modelLimitFor 查找模型的上下文限制,未配置的模型不做截断
+func modelLimitFor(text, modelName string) ModelLimit {
+	if limit, ok := matchModel(parseModelLimits(text), modelName); ok {
+		return limit
+	}
+	return ModelLimit{CharsPerToken: defaultCharsPerToken}
+}
+
+// EstimateTokens 粗略估算token数: 中日韩字符按1个token,其余按 CharsPerToken 个字符1个token
+func (l ModelLimit) EstimateTokens(text string) int {
+	var cjk, other int
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) || unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+
+	chars := l.CharsPerToken
+	if chars <= 0 {
+		chars = defaultCharsPerToken
+	}
+	return cjk + int(math.Ceil(float64(other)/chars))
+}
+
+// InputBudget 使用给定系统提示词时用户消息可用的token数,0为不限制
+func (l ModelLimit) InputBudget(system string) int {
+	if l.ContextTokens == 0 {
+		return 0
+	}
+
+	reserve := llmOutputReserve
+	if reserve > l.ContextTokens/4 {
+		reserve = l.ContextTokens / 4
+	}
+	budget := l.ContextTokens - reserve - l.EstimateTokens(system)
+	if budget < minInputBudget {
+		budget = minInputBudget
+	}
+	return budget
+}
+
+// fitTokens 返回 runes 开头不超过 budget 个token的最长部分的长度
+func (l ModelLimit) fitTokens(runes []rune, budget int) int {
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if l.EstimateTokens(string(runes[:mid])) <= budget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+// truncateTokens 按策略截断超出预算的文本,返回截断后的文本和是否发生了截断
+func (l ModelLimit) truncateTokens(text string, budget int, policy string) (string, bool) {
+	if budget == 0 || l.EstimateTokens(text) <= budget {
+		return text, false
+	}
+
+	const marker = "\n\n[...内容过长,已省略...]\n\n"
+	runes := []rune(text)
+	// 预算容不下省略标记时只保留开头
+	if budget <= l.EstimateTokens(marker) {
+		return string(runes[:l.fitTokens(runes, budget)]), true
+	}
+	budget -= l.EstimateTokens(marker)
+
+	if policy == model.TruncateHeadTail {
+		head := l.fitTokens(runes, budget/2)
+		tail := l.fitTokens(reverseRunes(runes[head:]), budget-l.EstimateTokens(string(runes[:head])))
+		return string(runes[:head]) + marker + string(runes[len(runes)-tail:]), true
+	}
+
+	head := l.fitTokens(runes, budget)
+	return string(runes[:head]) + marker, true
+}
+
+// splitChunks 按段落把文本切分为不超过 budget 个token的分段,
+// 单个段落超出预算时再按字符切分
+func (l ModelLimit) splitChunks(text string, budget int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+		currentTokens = 0
+	}
+
+	for _, para := range strings.Split(text, "\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+
+		tokens := l.EstimateTokens(para)
+		if tokens > budget {
+			flush()
+			runes := []rune(para)
+			for len(runes) > 0 {
+				n := l.fitTokens(runes, budget)
+				if n == 0 {
+					n = 1
+				}
+				if chunk := strings.TrimSpace(string(runes[:n])); chunk != "" {
+					chunks = append(chunks, chunk)
+				}
+				runes = runes[n:]
+			}
+			continue
+		}
+
+		if currentTokens+tokens > budget {
+			flush()
+		}
+		current.WriteString(para)
+		current.WriteString("\n")
+		currentTokens += tokens + 1
+	}
+	flush()
+
+	return chunks
+}
+
+func reverseRunes(runes []rune) []rune {
+	reversed := make([]rune, len(runes))
+	for i, r := range runes {
+		reversed[len(runes)-1-i] = r
+	}
+	return reversed
+}
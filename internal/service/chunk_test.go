@@ -0,0 +1,191 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"go-news/internal/model"
+)
+
+// mixedText 中英文混排的多段落文本
+func mixedText(paragraphs int) string {
+	lines := []string{
+		"OpenAI 发布了 GPT-4o mini,价格比 GPT-3.5 Turbo 低 60% 以上。",
+		"The model supports a 128K context window and outputs up to 16K tokens per request.",
+		"开发者可以通过 API 使用该模型,ChatGPT 免费用户也将默认切换到新模型。",
+		"Benchmarks show 82% on MMLU, ahead of Gemini Flash (77.9%) and Claude Haiku (73.8%).",
+		"日本語のテキストも含まれます。한국어 문장도 포함됩니다。",
+	}
+	var b strings.Builder
+	for i := 0; i < paragraphs; i++ {
+		b.WriteString(lines[i%len(lines)])
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+func stripSpace(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text  string
+		chars float64
+		want  int
+	}{
+		{"", 4, 0},
+		{"abcd", 4, 1},
+		{"abcde", 4, 2},
+		{"中文", 4, 2},
+		{"中文abc", 4, 3},
+		{"かなカナ한글", 4, 6},
+		{"abcdefg", 3.5, 2},
+		// 未配置时按默认值
+		{"abcdefgh", 0, 2},
+	}
+	for _, tt := range tests {
+		if got := (ModelLimit{CharsPerToken: tt.chars}).EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q, %v) = %d, want %d", tt.text, tt.chars, got, tt.want)
+		}
+	}
+}
+
+func TestFitTokens(t *testing.T) {
+	limit := ModelLimit{CharsPerToken: defaultCharsPerToken}
+	runes := []rune(mixedText(3))
+	for _, budget := range []int{0, 1, 2, 7, 30, 100, 10000} {
+		n := limit.fitTokens(runes, budget)
+		if got := limit.EstimateTokens(string(runes[:n])); got > budget {
+			t.Errorf("budget %d: 前 %d 个字符为 %d 个token,超出预算", budget, n, got)
+		}
+		// 必须是最长的前缀
+		if n < len(runes) && limit.EstimateTokens(string(runes[:n+1])) <= budget {
+			t.Errorf("budget %d: 前 %d 个字符不是最长前缀", budget, n)
+		}
+	}
+}
+
+func TestTruncateTokens(t *testing.T) {
+	text := mixedText(20)
+
+	tests := []struct {
+		name   string
+		chars  float64
+		budget int
+		policy string
+	}{
+		{"head/tiny", 4, 3, model.TruncateHead},
+		{"head/small", 4, 20, model.TruncateHead},
+		{"head/medium", 4, 150, model.TruncateHead},
+		{"head/large", 3.5, 500, model.TruncateHead},
+		{"head_tail/tiny", 4, 3, model.TruncateHeadTail},
+		{"head_tail/small", 4, 20, model.TruncateHeadTail},
+		{"head_tail/medium", 4, 150, model.TruncateHeadTail},
+		{"head_tail/odd", 4, 151, model.TruncateHeadTail},
+		{"head_tail/large", 3.5, 500, model.TruncateHeadTail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := ModelLimit{CharsPerToken: tt.chars}
+			if limit.EstimateTokens(text) <= tt.budget {
+				t.Fatalf("测试文本没有超出预算")
+			}
+
+			got, truncated := limit.truncateTokens(text, tt.budget, tt.policy)
+			if !truncated {
+				t.Fatalf("超出预算时应截断")
+			}
+			if tokens := limit.EstimateTokens(got); tokens > tt.budget {
+				t.Errorf("截断后为 %d 个token,超出预算 %d", tokens, tt.budget)
+			}
+
+			if tt.budget < 20 {
+				// 预算容不下省略标记时只保留开头
+				if !strings.HasPrefix(text, got) {
+					t.Errorf("截断结果不是原文开头: %q", got)
+				}
+				return
+			}
+			const marker = "\n\n[...内容过长,已省略...]\n\n"
+			parts := strings.SplitN(got, marker, 2)
+			if len(parts) != 2 {
+				t.Fatalf("缺少省略标记: %q", got)
+			}
+			head, tail := parts[0], parts[1]
+			if head == "" || !strings.HasPrefix(text, head) {
+				t.Errorf("没有保留开头: %q", got)
+			}
+			switch tt.policy {
+			case model.TruncateHeadTail:
+				if strings.TrimSpace(tail) == "" || !strings.HasSuffix(text, tail) {
+					t.Errorf("head_tail 没有保留结尾: %q", got)
+				}
+				// 开头约占一半预算
+				if headTokens := limit.EstimateTokens(head); headTokens > tt.budget/2 {
+					t.Errorf("开头为 %d 个token,超过预算的一半", headTokens)
+				}
+			case model.TruncateHead:
+				if tail != "" {
+					t.Errorf("head 不应保留结尾: %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncateTokensWithinBudget(t *testing.T) {
+	limit := ModelLimit{CharsPerToken: defaultCharsPerToken}
+	text := mixedText(2)
+	for _, budget := range []int{0, limit.EstimateTokens(text)} {
+		got, truncated := limit.truncateTokens(text, budget, model.TruncateHeadTail)
+		if truncated || got != text {
+			t.Errorf("budget %d: 不应截断", budget)
+		}
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	long := strings.Repeat("很长的段落没有换行", 40) + strings.Repeat(" lorem ipsum", 60)
+
+	tests := []struct {
+		name   string
+		text   string
+		chars  float64
+		budget int
+	}{
+		{"paragraphs", mixedText(30), 4, 60},
+		{"tight", mixedText(10), 4, 25},
+		{"one-token", mixedText(2), 4, 1},
+		{"long-paragraph", mixedText(3) + long + "\n" + mixedText(3), 4, 50},
+		{"chars-per-token", mixedText(12), 3.5, 40},
+		{"fits", mixedText(2), 4, 10000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := ModelLimit{CharsPerToken: tt.chars}
+			chunks := limit.splitChunks(tt.text, tt.budget)
+			if len(chunks) == 0 {
+				t.Fatal("没有分段")
+			}
+			for i, chunk := range chunks {
+				if tokens := limit.EstimateTokens(chunk); tokens > tt.budget {
+					t.Errorf("第 %d 段为 %d 个token,超出预算 %d", i, tokens, tt.budget)
+				}
+				if strings.TrimSpace(chunk) == "" {
+					t.Errorf("第 %d 段为空", i)
+				}
+			}
+			// 分段不丢失也不重复内容
+			if got, want := stripSpace(strings.Join(chunks, "")), stripSpace(tt.text); got != want {
+				t.Errorf("分段合并后与原文不同")
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	// SimHash 汉明距离不超过该值视为同一事件
+	storyMaxDistance = 8
+	// 只与该时间窗口内的文章比较
+	storyWindow = 48 * time.Hour
+	// 参与计算的正文长度上限
+	simHashBodyLimit = 2000
+	// 标题特征的权重,标题比正文更能代表事件
+	simHashTitleWeight = 3
+)
+
+type ClusterService struct {
+	db       *gorm.DB
+	entities *EntityService
+	mu       sync.Mutex // 并发抓取时避免同一事件创建多个 Story
+}
+
+func NewClusterService(db *gorm.DB) *ClusterService {
+	return &ClusterService{db: db, entities: NewEntityService(db)}
+}
+
+// Assign 计算文章的 SimHash 并归入相似的 Story,
+// 非代表文章标记为重复报道,不再交给LLM处理,只按规则提取实体
+func (s *ClusterService) Assign(article *model.Article) error {
+	article.SimHash = int64(SimHash(article.Title, article.BodyText()))
+	if article.SimHash == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 只与其他订阅源的文章聚类,同一订阅源的相似文章通常是系列文章或更正
+	var candidates []model.Article
+	s.db.Select("id", "title", "sim_hash", "story_id").
+		Where("id <> ? AND feed_id <> ? AND sim_hash <> 0 AND created_at >= ?",
+			article.ID, article.FeedID, time.Now().Add(-storyWindow)).
+		Find(&candidates)
+
+	var match *model.Article
+	best := storyMaxDistance + 1
+	for i := range candidates {
+		distance := hammingDistance(article.SimHash, candidates[i].SimHash)
+		if distance < best {
+			best = distance
+			match = &candidates[i]
+		}
+	}
+
+	if match == nil {
+		return s.db.Model(article).Update("sim_hash", article.SimHash).Error
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		storyID := match.StoryID
+		if storyID == nil {
+			story := model.Story{Title: match.Title, RepresentativeID: match.ID, ArticleCount: 1}
+			if err := tx.Create(&story).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Article{}).Where("id = ?", match.ID).Update("story_id", story.ID).Error; err != nil {
+				return err
+			}
+			storyID = &story.ID
+		}
+
+		if err := tx.Model(&model.Story{}).Where("id = ?", *storyID).
+			Update("article_count", gorm.Expr("article_count + 1")).Error; err != nil {
+			return err
+		}
+
+		article.StoryID = storyID
+		article.Status = model.StatusDuplicate
+		return tx.Model(article).Updates(map[string]interface{}{
+			"sim_hash": article.SimHash,
+			"story_id": article.StoryID,
+			"status":   article.Status,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.entities.AttachRules(article)
+}
+
+// Reassign 文章内容更新后重新计算 SimHash: 与代表文章不再相似的重复报道移出 Story 并重新排队,
+// 未归类的待处理文章重新聚类
+func (s *ClusterService) Reassign(article *model.Article) error {
+	hash := int64(SimHash(article.Title, article.BodyText()))
+	if hash == article.SimHash {
+		return nil
+	}
+	article.SimHash = hash
+
+	if article.Status == model.StatusDuplicate && article.StoryID != nil {
+		detached, err := s.detach(article)
+		if err != nil || !detached {
+			return err
+		}
+	} else if err := s.db.Model(article).Update("sim_hash", hash).Error; err != nil {
+		return err
+	}
+
+	if article.StoryID == nil && article.Status == model.StatusPending {
+		return s.Assign(article)
+	}
+	return nil
+}
+
+// detach 重复报道与代表文章不再相似时移出 Story,返回是否已移出
+func (s *ClusterService) detach(article *model.Article) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var representative model.Article
+	s.db.Select("articles.id", "articles.sim_hash").
+		Joins("JOIN stories ON stories.representative_id = articles.id").
+		Where("stories.id = ?", *article.StoryID).
+		Limit(1).Find(&representative)
+	if representative.ID != 0 && article.SimHash != 0 &&
+		hammingDistance(article.SimHash, representative.SimHash) <= storyMaxDistance {
+		return false, s.db.Model(article).Update("sim_hash", article.SimHash).Error
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Story{}).Where("id = ?", *article.StoryID).
+			Update("article_count", gorm.Expr("article_count - 1")).Error; err != nil {
+			return err
+		}
+		return tx.Model(article).Updates(map[string]interface{}{
+			"sim_hash": article.SimHash,
+			"story_id": nil,
+			"status":   model.StatusPending,
+		}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	article.StoryID = nil
+	article.Status = model.StatusPending
+	return true, nil
+}
+
+// Promote 代表文章处理失败或被过滤后,把 Story 中最早的重复报道提升为代表文章并放回待处理队列,
+// 避免整个事件因为一篇文章而没有摘要
+func (s *ClusterService) Promote(article *model.Article) error {
+	if article.StoryID == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var story model.Story
+	if err := s.db.First(&story, *article.StoryID).Error; err != nil {
+		return err
+	}
+	if story.RepresentativeID != article.ID {
+		return nil
+	}
+
+	var next model.Article
+	if err := s.db.Select("id", "title").
+		Where("story_id = ? AND status = ?", story.ID, model.StatusDuplicate).
+		Order("id").Limit(1).Find(&next).Error; err != nil || next.ID == 0 {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&story).Updates(map[string]interface{}{
+			"representative_id": next.ID,
+			"title":             next.Title,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("status", model.StatusPending).Error
+	})
+}
+
+func hammingDistance(a, b int64) int {
+	return bits.OnesCount64(uint64(a ^ b))
+}
+
+// SimHash 计算标题和正文的64位 SimHash
+func SimHash(title, body string) uint64 {
+	var weights [64]int
+	add := func(token string, weight int) {
+		h := fnv.New64a()
+		h.Write([]byte(token))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i] += weight
+			} else {
+				weights[i] -= weight
+			}
+		}
+	}
+
+	var count int
+	for _, token := range tokenize(title) {
+		add(token, simHashTitleWeight)
+		count++
+	}
+
+	runes := []rune(body)
+	if len(runes) > simHashBodyLimit {
+		runes = runes[:simHashBodyLimit]
+	}
+	for _, token := range tokenize(string(runes)) {
+		add(token, 1)
+		count++
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	var hash uint64
+	for i := 0; i < 64; i++ {
+		if weights[i] > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// tokenize 分词: 拉丁文字按单词切分,中日韩文字使用二元组
+func tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var han []rune
+
+	flushWord := func() {
+		if len(word) > 1 {
+			tokens = append(tokens, string(word))
+		}
+		word = word[:0]
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			tokens = append(tokens, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			tokens = append(tokens, string(han[i:i+2]))
+		}
+		han = han[:0]
+	}
+
+	for _, r := range strings.ToLower(stripTags(text)) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+
+	return tokens
+}
+
+// PlainText 去掉 HTML 标签并合并多余空白
+func PlainText(text string) string {
+	return strings.Join(strings.Fields(stripTags(text)), " ")
+}
+
+// stripTags 去掉 HTML 标签,Feed 描述中常带有标签
+func stripTags(text string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range text {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+			b.WriteRune(' ')
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
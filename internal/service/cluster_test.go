@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-news/config"
+	"go-news/internal/model"
+)
+
+const (
+	clusterTitle = "OpenAI releases GPT-4o mini, a cheaper small model"
+	clusterBody  = "OpenAI today released GPT-4o mini, its most cost-efficient small model. " +
+		"It scores 82% on MMLU and costs 15 cents per million input tokens, " +
+		"more than 60% cheaper than GPT-3.5 Turbo. Developers can use it through the API starting today."
+)
+
+// createArticle 保存文章并聚类
+func createArticle(t *testing.T, s *FeedService, feedID uint, guid, title, content string) *model.Article {
+	t.Helper()
+	article := &model.Article{FeedID: feedID, GUID: guid, Title: title, Content: content, Link: "https://example.com/" + guid}
+	if err := s.db.Create(article).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.cluster.Assign(article); err != nil {
+		t.Fatal(err)
+	}
+	return article
+}
+
+func reload(t *testing.T, s *FeedService, id uint) model.Article {
+	t.Helper()
+	var article model.Article
+	if err := s.db.First(&article, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return article
+}
+
+func TestAssignAcrossFeeds(t *testing.T) {
+	s := newTestFeedService(t, config.FetchConfig{})
+
+	first := createArticle(t, s, 1, "a", clusterTitle, clusterBody)
+	// 同一订阅源的相似文章不聚类
+	sameFeed := createArticle(t, s, 1, "b", clusterTitle, clusterBody)
+	if sameFeed.StoryID != nil || sameFeed.Status != model.StatusPending {
+		t.Errorf("同一订阅源的文章被标记为重复: story=%v status=%d", sameFeed.StoryID, sameFeed.Status)
+	}
+
+	other := createArticle(t, s, 2, "c", clusterTitle, clusterBody)
+	if other.StoryID == nil || other.Status != model.StatusDuplicate {
+		t.Fatalf("其他订阅源的相似文章应标记为重复: story=%v status=%d", other.StoryID, other.Status)
+	}
+	var story model.Story
+	s.db.First(&story, *other.StoryID)
+	if story.ArticleCount != 2 || (story.RepresentativeID != first.ID && story.RepresentativeID != sameFeed.ID) {
+		t.Errorf("story = %+v", story)
+	}
+}
+
+func TestPromoteRepresentative(t *testing.T) {
+	tests := []struct {
+		name string
+		fail func(p *ProcessorService, article *model.Article) error
+	}{
+		{"failed", func(p *ProcessorService, article *model.Article) error {
+			article.Attempts = processMaxAttempts - 1
+			return p.recordFailure(article, errors.New("HTTP 500"))
+		}},
+		{"filtered", func(p *ProcessorService, article *model.Article) error {
+			article.Status = model.StatusFiltered
+			if err := p.save(article, nil, nil); err != nil {
+				return err
+			}
+			p.promote(article)
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestFeedService(t, config.FetchConfig{})
+			p := NewProcessorService(s.db, nil)
+
+			representative := createArticle(t, s, 1, "a", clusterTitle, clusterBody)
+			second := createArticle(t, s, 2, "b", clusterTitle, clusterBody)
+			third := createArticle(t, s, 3, "c", clusterTitle, clusterBody)
+
+			article := reload(t, s, representative.ID)
+			if err := tt.fail(p, &article); err != nil {
+				t.Fatal(err)
+			}
+
+			var story model.Story
+			s.db.First(&story, *second.StoryID)
+			if story.RepresentativeID != second.ID {
+				t.Errorf("代表文章 = %d, want %d", story.RepresentativeID, second.ID)
+			}
+			if got := reload(t, s, second.ID); got.Status != model.StatusPending {
+				t.Errorf("新的代表文章 status = %d, want 待处理", got.Status)
+			}
+			if got := reload(t, s, third.ID); got.Status != model.StatusDuplicate {
+				t.Errorf("其余文章 status = %d, want 重复报道", got.Status)
+			}
+
+			// 非代表文章失败时不更换
+			next := reload(t, s, third.ID)
+			p.promote(&next)
+			s.db.First(&story, story.ID)
+			if story.RepresentativeID != second.ID {
+				t.Errorf("非代表文章更换了代表文章: %d", story.RepresentativeID)
+			}
+		})
+	}
+}
+
+func TestApplyUpdateReassignsStory(t *testing.T) {
+	s := newTestFeedService(t, config.FetchConfig{})
+	feed := model.Feed{Name: "b", URL: "https://b.example.com/feed"}
+	s.db.Create(&feed)
+
+	representative := createArticle(t, s, feed.ID+1, "a", clusterTitle, clusterBody)
+	duplicate := createArticle(t, s, feed.ID, "b", clusterTitle, clusterBody)
+	if duplicate.Status != model.StatusDuplicate {
+		t.Fatalf("status = %d, want 重复报道", duplicate.Status)
+	}
+
+	// 小幅修改后仍属于同一事件
+	existing := reload(t, s, duplicate.ID)
+	minor := &model.Article{Title: clusterTitle, Content: clusterBody + " Updated."}
+	if err := s.applyUpdate(context.Background(), &feed, &existing, minor); err != nil {
+		t.Fatal(err)
+	}
+	got := reload(t, s, duplicate.ID)
+	if got.StoryID == nil || got.Status != model.StatusDuplicate {
+		t.Fatalf("小幅修改后 story=%v status=%d", got.StoryID, got.Status)
+	}
+	if want := int64(SimHash(minor.Title, minor.Content)); got.SimHash != want {
+		t.Errorf("sim_hash = %d, want %d", got.SimHash, want)
+	}
+
+	// 内容换成另一个事件后移出 Story 并重新排队
+	other := &model.Article{
+		Title:   "Rust 1.80 stabilizes LazyCell and exclusive range patterns",
+		Content: "The Rust team announced version 1.80 with lazy types, exclusive ranges in patterns and checked cfg names.",
+	}
+	if err := s.applyUpdate(context.Background(), &feed, &got, other); err != nil {
+		t.Fatal(err)
+	}
+	got = reload(t, s, duplicate.ID)
+	if got.StoryID != nil || got.Status != model.StatusPending {
+		t.Errorf("内容变化后 story=%v status=%d, want 移出事件并待处理", got.StoryID, got.Status)
+	}
+	if want := int64(SimHash(other.Title, other.Content)); got.SimHash != want {
+		t.Errorf("sim_hash = %d, want %d", got.SimHash, want)
+	}
+	var story model.Story
+	s.db.First(&story, *duplicate.StoryID)
+	if story.ArticleCount != 1 || story.RepresentativeID != representative.ID {
+		t.Errorf("story = %+v", story)
+	}
+}
+
+// 重复报道不交给LLM处理,聚类时按规则提取实体
+func TestAssignAttachesEntitiesToDuplicates(t *testing.T) {
+	title := "Backdoor found in xz Utils 5.6.1, tracked as CVE-2024-3094"
+	body := "A malicious backdoor was discovered in the xz compression library, versions 5.6.0 and 5.6.1. " +
+		"Red Hat urges users of Fedora Rawhide to stop using affected systems immediately."
+
+	for _, mode := range []string{model.EntityExtractionRules, model.EntityExtractionOff} {
+		t.Run(mode, func(t *testing.T) {
+			s := newTestFeedService(t, config.FetchConfig{})
+			s.db.Create(&model.Config{Key: model.ConfigEntityExtraction, Value: mode})
+
+			representative := createArticle(t, s, 1, "a", title, body)
+			duplicate := createArticle(t, s, 2, "b", title, body)
+			if duplicate.Status != model.StatusDuplicate {
+				t.Fatalf("status = %d, want 重复报道", duplicate.Status)
+			}
+
+			var entities []model.Entity
+			s.db.Model(duplicate).Association("Entities").Find(&entities)
+			names := make(map[string]bool)
+			for _, e := range entities {
+				names[e.Name] = true
+			}
+			if want := mode == model.EntityExtractionRules; names["CVE-2024-3094"] != want {
+				t.Errorf("重复报道的实体 = %v, 包含 CVE-2024-3094: %v", names, want)
+			}
+			// 代表文章的实体在处理时提取
+			if n := s.db.Model(representative).Association("Entities").Count(); n != 0 {
+				t.Errorf("代表文章聚类时不应提取实体, got %d", n)
+			}
+		})
+	}
+}
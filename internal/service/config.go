@@ -0,0 +1,25 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+// configValue 读取单个配置项,不存在时返回空字符串
+func configValue(db *gorm.DB, key string) string {
+	var config model.Config
+	db.Where("key = ?", key).Limit(1).Find(&config)
+	return config.Value
+}
+
+// parseIntConfig 解析整数配置,为空、无效或为负数时使用默认值
+func parseIntConfig(value string, def int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
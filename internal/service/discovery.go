@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// 网页中未声明Feed时尝试的常见路径
+var commonFeedPaths = []string{
+	"/feed", "/feed/", "/rss", "/rss.xml", "/feed.xml",
+	"/atom.xml", "/index.xml", "/feeds/posts/default", "/feed.json",
+}
+
+// Feed 的 MIME 类型
+var feedMIMETypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+	"application/xml":       true,
+	"text/xml":              true,
+}
+
+// DiscoveredFeed 发现的Feed
+type DiscoveredFeed struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+// Discover 从网址发现Feed: 网址本身是Feed时直接返回,否则读取
+// <link rel="alternate"> 和常见路径,逐个解析验证
+func (s *FeedService) Discover(ctx context.Context, pageURL string) ([]DiscoveredFeed, error) {
+	base, err := url.Parse(strings.TrimSpace(pageURL))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("无效的URL: %s", pageURL)
+	}
+
+	body, err := s.get(ctx, base.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed, err := parseFeed(body); err == nil {
+		return []DiscoveredFeed{{URL: base.String(), Title: parsed.Title, Type: parsed.FeedType}}, nil
+	}
+
+	candidates := linkCandidates(base, body)
+	if len(candidates) == 0 {
+		for _, path := range commonFeedPaths {
+			ref, _ := url.Parse(path)
+			candidates = append(candidates, base.ResolveReference(ref).String())
+		}
+	}
+
+	feeds := make([]DiscoveredFeed, 0)
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+
+		if feed, err := s.validateFeed(ctx, candidate); err == nil {
+			feeds = append(feeds, *feed)
+		}
+	}
+
+	return feeds, nil
+}
+
+// validateFeed 下载并解析Feed,返回其标题
+func (s *FeedService) validateFeed(ctx context.Context, feedURL string) (*DiscoveredFeed, error) {
+	body, err := s.get(ctx, feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := parseFeed(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiscoveredFeed{URL: feedURL, Title: parsed.Title, Type: parsed.FeedType}, nil
+}
+
+func (s *FeedService) get(ctx context.Context, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "go-news/1.0")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP状态码异常: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+}
+
+// linkCandidates 从网页 <link rel="alternate"> 中读取Feed地址
+func linkCandidates(base *url.URL, body []byte) []string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	doc.Find(`link[rel~="alternate"], link[rel~="feed"]`).Each(func(_ int, link *goquery.Selection) {
+		mimeType := strings.ToLower(strings.TrimSpace(link.AttrOr("type", "")))
+		if mimeType != "" && !feedMIMETypes[mimeType] {
+			return
+		}
+
+		href := strings.TrimSpace(link.AttrOr("href", ""))
+		ref, err := url.Parse(href)
+		if href == "" || err != nil {
+			return
+		}
+		candidates = append(candidates, base.ResolveReference(ref).String())
+	})
+
+	return candidates
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// 每篇文章最多保存的实体数
+	maxArticleEntities = 20
+	// 实体时间线统计的天数
+	entityTimelineDays = 90
+)
+
+var (
+	cvePattern = regexp.MustCompile(`(?i)\bCVE-\d{4}-\d{4,7}\b`)
+	// 产品名紧跟版本号,如 Go 1.25、Linux 6.12.1、iOS 18.1
+	versionPattern = regexp.MustCompile(`\b([A-Za-z][A-Za-z0-9+#]+) v?(\d+(?:\.\d+){1,3})\b`)
+)
+
+// versionStopWords 数字前常见的非产品名词。标题常按 Title Case 书写,
+// 句首或大写的介词、动词后跟小数时容易被误认为产品版本,如 "The 1.5"、"Raises 6.6"
+var versionStopWords = toSet(
+	// 版本号本身和文档结构
+	"version", "ver", "v", "release", "update", "figure", "fig", "table", "section", "chapter", "step", "page", "part",
+	"phase", "stage", "round", "tier", "level", "grade", "mark", "score", "scores", "rated", "rating", "cvss",
+	// 冠词、介词、连词、代词
+	"the", "a", "an", "in", "on", "at", "by", "for", "from", "to", "of", "with", "about", "over", "under", "around",
+	"nearly", "almost", "only", "just", "up", "down", "after", "before", "since", "than", "and", "or", "but", "vs",
+	"is", "was", "are", "were", "be", "has", "have", "had", "its", "it", "this", "that", "these", "those", "all",
+	"top", "new", "now", "into", "via", "per", "more", "less", "some",
+	// 标题中描述数量变化的动词
+	"get", "gets", "got", "raise", "raises", "raised", "rise", "rises", "rose", "fall", "falls", "fell",
+	"grow", "grows", "grew", "drop", "drops", "dropped", "jump", "jumps", "jumped", "hit", "hits", "reach", "reaches", "reached",
+	"cut", "cuts", "add", "adds", "added", "lose", "loses", "lost", "spend", "spends", "spent", "pay", "pays", "paid",
+)
+
+// versionQuantityWords 数字后跟这些词时是数量而不是版本号,如 "1.5 million"
+var versionQuantityWords = toSet(
+	"thousand", "million", "billion", "trillion", "percent", "times", "x",
+	"k", "m", "b", "bn", "kb", "mb", "gb", "tb", "ms", "s", "sec", "seconds", "minutes", "hours", "days",
+	"years", "users", "people", "points", "stars", "inch", "inches", "ghz", "mhz", "w", "kw", "mw",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// ExtractedEntity LLM 或规则提取出的实体
+type ExtractedEntity struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// EntityCount 实体及提及它的文章数、首次和最近出现的日期
+type EntityCount struct {
+	ID        uint   `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Count     int64  `json:"count"`
+	FirstSeen string `json:"first_seen"`
+	LastSeen  string `json:"last_seen"`
+}
+
+// EntityPoint 某一天提及实体的文章数
+type EntityPoint struct {
+	Period string `json:"period"`
+	Count  int64  `json:"count"`
+}
+
+type EntityService struct {
+	db *gorm.DB
+}
+
+func NewEntityService(db *gorm.DB) *EntityService {
+	return &EntityService{db: db}
+}
+
+// ExtractRules 按规则提取漏洞编号和带版本号的产品,不依赖LLM
+func ExtractRules(text string) []ExtractedEntity {
+	text = stripTags(text)
+
+	var entities []ExtractedEntity
+	for _, id := range cvePattern.FindAllString(text, -1) {
+		entities = append(entities, ExtractedEntity{Type: model.EntityCVE, Name: strings.ToUpper(id)})
+	}
+	for _, m := range versionPattern.FindAllStringSubmatchIndex(text, -1) {
+		name, version := text[m[2]:m[3]], text[m[4]:m[5]]
+		// 产品名至少含一个大写字母,排除 up 2.5 之类的普通词
+		if versionStopWords[strings.ToLower(name)] || strings.ToLower(name) == name || isQuantity(text[m[1]:]) {
+			continue
+		}
+		entities = append(entities, ExtractedEntity{Type: model.EntityVersion, Name: name + " " + version})
+	}
+	return entities
+}
+
+// isQuantity 数字之后的文本表明前面是数量 (百分比、金额单位、计量单位) 而不是版本号
+func isQuantity(rest string) bool {
+	if strings.HasPrefix(rest, "%") || strings.HasPrefix(rest, "万") || strings.HasPrefix(rest, "亿") || strings.HasPrefix(rest, "倍") {
+		return true
+	}
+	rest = strings.TrimLeft(rest, " ")
+	end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(rest)
+	}
+	return versionQuantityWords[strings.ToLower(rest[:end])]
+}
+
+// AttachRules 按规则提取实体并关联到文章,用于不交给LLM处理的重复报道。提取方式为 off 时跳过
+func (s *EntityService) AttachRules(article *model.Article) error {
+	if configValue(s.db, model.ConfigEntityExtraction) == model.EntityExtractionOff {
+		return nil
+	}
+	entities, err := s.Resolve(ExtractRules(article.Title + "\n\n" + article.BodyText()))
+	if err != nil || len(entities) == 0 {
+		return err
+	}
+	return s.db.Model(article).Association("Entities").Replace(entities)
+}
+
+// Resolve 规范化实体 (去空白、校验类型、按类型和小写名称去重) 并查找或创建对应的实体
+func (s *EntityService) Resolve(items []ExtractedEntity) ([]model.Entity, error) {
+	entities := make([]model.Entity, 0, len(items))
+	seen := make(map[string]bool)
+	for _, item := range items {
+		typ := strings.ToLower(strings.TrimSpace(item.Type))
+		name := strings.Join(strings.Fields(item.Name), " ")
+		if typ == model.EntityCVE {
+			name = strings.ToUpper(name)
+		}
+		key := strings.ToLower(name)
+		if name == "" || len([]rune(name)) > 100 || !containsString(model.EntityTypes, typ) || seen[typ+":"+key] {
+			continue
+		}
+		seen[typ+":"+key] = true
+
+		entity, err := s.findOrCreate(typ, key, name)
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+		if len(entities) >= maxArticleEntities {
+			break
+		}
+	}
+	return entities, nil
+}
+
+// findOrCreate 查找或创建实体,新实体的名称使用 name。
+// 与标签相同,并发创建同一实体时忽略插入冲突并重新查询
+func (s *EntityService) findOrCreate(typ, key, name string) (model.Entity, error) {
+	var entity model.Entity
+	where := model.Entity{Type: typ, Key: key}
+	if err := s.db.Where(where).Limit(1).Find(&entity).Error; err != nil || entity.ID != 0 {
+		return entity, err
+	}
+	err := s.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.Entity{Type: typ, Key: key, Name: name}).Error
+	if err != nil {
+		return entity, err
+	}
+	err = s.db.Where(where).First(&entity).Error
+	return entity, err
+}
+
+// Index 实体列表,按提及的文章数排序。entityType 和 query 为空时不过滤
+func (s *EntityService) Index(entityType, query string, limit int) ([]EntityCount, error) {
+	q := s.db.Table("entities").
+		Select("entities.id, entities.type, entities.name, COUNT(*) AS count, " +
+			"MIN(substr(articles.pub_date, 1, 10)) AS first_seen, MAX(substr(articles.pub_date, 1, 10)) AS last_seen").
+		Joins("JOIN article_entities ON article_entities.entity_id = entities.id").
+		Joins("JOIN articles ON articles.id = article_entities.article_id")
+	if entityType != "" {
+		q = q.Where("entities.type = ?", entityType)
+	}
+	if query = strings.ToLower(strings.TrimSpace(query)); query != "" {
+		q = q.Where("entities.key LIKE ?", "%"+query+"%")
+	}
+
+	var entities []EntityCount
+	err := q.Group("entities.id").
+		Order("count DESC, last_seen DESC").
+		Limit(limit).
+		Scan(&entities).Error
+	return entities, err
+}
+
+// Get 获取单个实体
+func (s *EntityService) Get(id uint) (*model.Entity, error) {
+	var entity model.Entity
+	if err := s.db.First(&entity, id).Error; err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// Timeline 最近 entityTimelineDays 天每天提及实体的文章数,只包含有提及的日期
+func (s *EntityService) Timeline(id uint) ([]EntityPoint, error) {
+	var points []EntityPoint
+	err := s.db.Table("article_entities").
+		Select("substr(articles.pub_date, 1, 10) AS period, COUNT(*) AS count").
+		Joins("JOIN articles ON articles.id = article_entities.article_id").
+		Where("article_entities.entity_id = ?", id).
+		Where("articles.pub_date >= ?", time.Now().AddDate(0, 0, -entityTimelineDays)).
+		Group("period").
+		Order("period").
+		Scan(&points).Error
+	return points, err
+}
+
+// WhereEntity 限定查询提及指定实体的文章,entity 为实体ID或名称 (不区分大小写和类型)
+func WhereEntity(query *gorm.DB, entity string) *gorm.DB {
+	sub := query.Session(&gorm.Session{NewDB: true}).Table("article_entities").
+		Select("article_entities.article_id")
+	if id, err := strconv.Atoi(entity); err == nil {
+		sub = sub.Where("article_entities.entity_id = ?", id)
+	} else {
+		sub = sub.Joins("JOIN entities ON entities.id = article_entities.entity_id").
+			Where("entities.key = ?", strings.ToLower(strings.Join(strings.Fields(entity), " ")))
+	}
+	return query.Where("articles.id IN (?)", sub)
+}
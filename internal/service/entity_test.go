@@ -0,0 +1,120 @@
+package service
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go-news/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 临时目录中的 SQLite 数据库,并发测试需要文件数据库和忙等待
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestExtractRules(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Go 1.25 is released", []string{"version:Go 1.25"}},
+		{"Linux 6.12 brings real-time support to mainline", []string{"version:Linux 6.12"}},
+		{"Apple releases iOS 18.1 and macOS 15.1 with Apple Intelligence", []string{"version:iOS 18.1", "version:macOS 15.1"}},
+		{"Kubernetes v1.32: Penelope", []string{"version:Kubernetes 1.32"}},
+		{"Firefox 133.0.3 fixes a crash on startup", []string{"version:Firefox 133.0.3"}},
+		{"Upgrade to PostgreSQL 17.2 now", []string{"version:PostgreSQL 17.2"}},
+		{"Backdoor in xz 5.6.0 tracked as cve-2024-3094", []string{"cve:CVE-2024-3094"}},
+		{"<p>CVE-2024-6387 (regreSSHion) affects OpenSSH 9.8</p>", []string{"cve:CVE-2024-6387", "version:OpenSSH 9.8"}},
+		// 句首或 Title Case 标题中的普通词
+		{"The 1.5 million users affected by the breach", nil},
+		{"In 2.0 we rewrote the parser from scratch", nil},
+		{"OpenAI Raises 6.6 Billion at a 157 Billion Valuation", nil},
+		{"Nvidia Shares Jump 2.5% After Earnings", nil},
+		{"Critical Bug Scores 9.8 on CVSS Scale", nil},
+		{"Version 2.0 of the HTTP spec is out", nil},
+		{"Rust Compiles 1.4 Times Faster With New Backend", nil},
+		{"About 1.2 GB of data was exposed", nil},
+		{"特斯拉 Model 3 降价 1.5 万元", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, e := range ExtractRules(tt.text) {
+			got = append(got, e.Type+":"+e.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExtractRules(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEntityResolve(t *testing.T) {
+	s := NewEntityService(newTestDB(t, &model.Entity{}))
+
+	first, err := s.Resolve([]ExtractedEntity{
+		{Type: "company", Name: " Red  Hat "},
+		{Type: "Company", Name: "red hat"},
+		{Type: "cve", Name: "cve-2024-3094"},
+		{Type: "unknown", Name: "x"},
+		{Type: "product", Name: ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 || first[0].Name != "Red Hat" || first[1].Name != "CVE-2024-3094" {
+		t.Fatalf("Resolve() = %+v", first)
+	}
+
+	// 已有实体保留首次出现时的写法
+	second, err := s.Resolve([]ExtractedEntity{{Type: "company", Name: "RED HAT"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 1 || second[0].ID != first[0].ID || second[0].Name != "Red Hat" {
+		t.Errorf("Resolve() = %+v, want %+v", second, first[0])
+	}
+}
+
+func TestEntityResolveConcurrent(t *testing.T) {
+	s := NewEntityService(newTestDB(t, &model.Entity{}))
+	items := []ExtractedEntity{
+		{Type: "company", Name: "OpenAI"},
+		{Type: "product", Name: "ChatGPT"},
+		{Type: "cve", Name: "CVE-2024-3094"},
+	}
+
+	const workers = 8
+	results := make([][]model.Entity, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.Resolve(items)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < workers; i++ {
+		if errs[i] != nil {
+			t.Fatalf("worker %d: %v", i, errs[i])
+		}
+		if !reflect.DeepEqual(results[i], results[0]) || len(results[i]) != len(items) {
+			t.Errorf("worker %d: %+v, want %+v", i, results[i], results[0])
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+// EvaluationService 管理黄金集标注,并用指定的筛选提示词版本和模型评估黄金集
+type EvaluationService struct {
+	db        *gorm.DB
+	processor *ProcessorService
+}
+
+func NewEvaluationService(db *gorm.DB, processor *ProcessorService) *EvaluationService {
+	return &EvaluationService{db: db, processor: processor}
+}
+
+// Label 将文章加入黄金集或修改标注
+func (s *EvaluationService) Label(articleID uint, worth bool, score *int, note string) (*model.GoldenLabel, error) {
+	if score != nil && (*score < 0 || *score > 100) {
+		return nil, fmt.Errorf("相关度应在0到100之间")
+	}
+
+	var article model.Article
+	if err := s.db.Select("id").First(&article, articleID).Error; err != nil {
+		return nil, fmt.Errorf("文章不存在")
+	}
+
+	label := model.GoldenLabel{ArticleID: articleID}
+	err := s.db.Where("article_id = ?", articleID).
+		Assign(map[string]interface{}{"worth": worth, "score": score, "note": note}).
+		FirstOrCreate(&label).Error
+	if err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
+
+// Unlabel 将文章移出黄金集
+func (s *EvaluationService) Unlabel(articleID uint) error {
+	return s.db.Where("article_id = ?", articleID).Delete(&model.GoldenLabel{}).Error
+}
+
+// Labels 黄金集的全部标注
+func (s *EvaluationService) Labels() ([]model.GoldenLabel, error) {
+	var labels []model.GoldenLabel
+	err := s.db.Preload("Article", selectArticleBrief).Order("id DESC").Find(&labels).Error
+	return labels, err
+}
+
+// Start 创建评估并在后台运行
+func (s *EvaluationService) Start(promptVersionID uint, modelName string) (*model.Evaluation, error) {
+	evaluation, err := s.Create(promptVersionID, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 使用独立的 context,不受 HTTP 请求生命周期影响
+	go func() {
+		if err := s.Run(context.Background(), evaluation); err != nil {
+			log.Printf("[Processor] 评估 #%d 失败: %v", evaluation.ID, err)
+		}
+	}()
+	return evaluation, nil
+}
+
+// Create 创建评估记录。promptVersionID 为0时使用当前的全局筛选提示词,
+// modelName 为空时使用配置的模型
+func (s *EvaluationService) Create(promptVersionID uint, modelName string) (*model.Evaluation, error) {
+	var version *model.PromptVersion
+	var err error
+	if promptVersionID == 0 {
+		version, err = s.processor.prompts.Record(model.ConfigPromptFilter, model.PromptScopeGlobal,
+			configValue(s.db, model.ConfigPromptFilter))
+		if err == nil && version == nil {
+			err = fmt.Errorf("筛选提示词未配置")
+		}
+	} else {
+		version, err = s.processor.prompts.GetVersion(promptVersionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version.Key != model.ConfigPromptFilter && version.Key != model.ConfigPromptCombined {
+		return nil, fmt.Errorf("只能评估筛选或合并模式的提示词")
+	}
+
+	var total int64
+	s.db.Model(&model.GoldenLabel{}).Count(&total)
+	if total == 0 {
+		return nil, fmt.Errorf("黄金集为空,请先在文章列表中标注文章")
+	}
+
+	cfg, err := s.processor.llm.ConfigForModel(modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation := &model.Evaluation{
+		PromptVersionID: version.ID,
+		PromptVersion:   version,
+		Model:           cfg.Model,
+		Status:          model.ExperimentRunning,
+		Total:           int(total),
+	}
+	if err := s.db.Create(evaluation).Error; err != nil {
+		return nil, err
+	}
+	return evaluation, nil
+}
+
+// Run 逐篇处理黄金集并计算指标,完成后保存结果
+func (s *EvaluationService) Run(ctx context.Context, evaluation *model.Evaluation) error {
+	version := evaluation.PromptVersion
+	if version == nil {
+		var err error
+		if version, err = s.processor.prompts.GetVersion(evaluation.PromptVersionID); err != nil {
+			return s.finish(evaluation, nil, err)
+		}
+	}
+
+	var labels []model.GoldenLabel
+	s.db.Preload("Article").Find(&labels)
+	log.Printf("[Processor] 开始评估 #%d: %s v%d, 模型 %s, %d 篇文章",
+		evaluation.ID, version.Key, version.Version, evaluation.Model, len(labels))
+
+	cfg, err := s.processor.llm.ConfigForModel(evaluation.Model)
+	if err != nil {
+		return s.finish(evaluation, nil, err)
+	}
+	schema := filterSchema
+	if version.Key == model.ConfigPromptCombined {
+		schema = combinedSchema
+	}
+
+	items := make([]model.EvaluationItem, 0, len(labels))
+	for _, label := range labels {
+		if label.Article == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return s.finish(evaluation, items, err)
+		}
+		if err := s.processor.usage.CheckBudget(); err != nil {
+			return s.finish(evaluation, items, err)
+		}
+
+		item := model.EvaluationItem{
+			EvaluationID:  evaluation.ID,
+			ArticleID:     label.ArticleID,
+			Article:       label.Article,
+			ExpectedWorth: label.Worth,
+			ExpectedScore: label.Score,
+		}
+
+		prompt, err := s.processor.prompts.Render(version, label.Article)
+		if err == nil {
+			var result *FilterResult
+			meta := ChatMeta{ArticleID: label.ArticleID, Stage: model.StageEvaluation, Model: evaluation.Model}
+			if result, _, err = s.processor.filter(ctx, cfg, meta, label.Article, prompt, schema); err == nil {
+				item.Worth = s.processor.worthReading(result)
+				item.Score = result.Score
+				item.Reason = result.Reason
+			}
+		}
+		if err != nil {
+			item.Error = err.Error()
+		}
+		items = append(items, item)
+	}
+
+	return s.finish(evaluation, items, nil)
+}
+
+// finish 计算指标并保存评估结果,runErr 不为空时标记为失败
+func (s *EvaluationService) finish(evaluation *model.Evaluation, items []model.EvaluationItem, runErr error) error {
+	computeMetrics(evaluation, items)
+
+	now := time.Now()
+	evaluation.FinishedAt = &now
+	evaluation.Status = model.ExperimentDone
+	if runErr != nil {
+		evaluation.Status = model.ExperimentFailed
+		evaluation.Error = runErr.Error()
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if len(items) > 0 {
+			if err := tx.Omit("Article").Create(&items).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit("PromptVersion", "Results").Save(evaluation).Error
+	})
+	if err != nil {
+		return err
+	}
+	evaluation.Results = items
+
+	log.Printf("[Processor] 评估 #%d 完成: 精确率 %.2f, 召回率 %.2f, 一致率 %.2f",
+		evaluation.ID, evaluation.Precision, evaluation.Recall, evaluation.Agreement)
+	return runErr
+}
+
+// computeMetrics 以"值得阅读"为正类统计混淆矩阵、精确率、召回率、一致率和相关度误差,
+// 调用失败的文章不计入
+func computeMetrics(evaluation *model.Evaluation, items []model.EvaluationItem) {
+	evaluation.TruePositive, evaluation.FalsePositive = 0, 0
+	evaluation.TrueNegative, evaluation.FalseNegative = 0, 0
+	evaluation.Failed = 0
+
+	var scoreError float64
+	var scored int
+	for _, item := range items {
+		if item.Error != "" {
+			evaluation.Failed++
+			continue
+		}
+		switch {
+		case item.Worth && item.ExpectedWorth:
+			evaluation.TruePositive++
+		case item.Worth && !item.ExpectedWorth:
+			evaluation.FalsePositive++
+		case !item.Worth && !item.ExpectedWorth:
+			evaluation.TrueNegative++
+		default:
+			evaluation.FalseNegative++
+		}
+		if item.ExpectedScore != nil {
+			diff := float64(item.Score - *item.ExpectedScore)
+			if diff < 0 {
+				diff = -diff
+			}
+			scoreError += diff
+			scored++
+		}
+	}
+
+	tp, fp := evaluation.TruePositive, evaluation.FalsePositive
+	tn, fn := evaluation.TrueNegative, evaluation.FalseNegative
+	evaluation.Precision = ratio(tp, tp+fp)
+	evaluation.Recall = ratio(tp, tp+fn)
+	evaluation.Agreement = ratio(tp+tn, tp+fp+tn+fn)
+	evaluation.ScoreError = 0
+	if scored > 0 {
+		evaluation.ScoreError = scoreError / float64(scored)
+	}
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// List 最近的评估
+func (s *EvaluationService) List(limit int) ([]model.Evaluation, error) {
+	var evaluations []model.Evaluation
+	err := s.db.Preload("PromptVersion").Order("id DESC").Limit(limit).Find(&evaluations).Error
+	return evaluations, err
+}
+
+// Get 获取评估及逐篇结果
+func (s *EvaluationService) Get(id uint) (*model.Evaluation, error) {
+	var evaluation model.Evaluation
+	err := s.db.Preload("PromptVersion").
+		Preload("Results", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Results.Article", selectArticleBrief).
+		First(&evaluation, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &evaluation, nil
+}
+
+// selectArticleBrief 列表中只需要文章的标题和当前处理结果
+func selectArticleBrief(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "feed_id", "title", "link", "status", "score", "category", "summary")
+}
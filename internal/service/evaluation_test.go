@@ -0,0 +1,79 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"go-news/internal/model"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestComputeMetrics(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []model.EvaluationItem
+		want  model.Evaluation
+	}{
+		{
+			name: "mixed",
+			items: []model.EvaluationItem{
+				{ExpectedWorth: true, Worth: true, Score: 80, ExpectedScore: intPtr(90)},
+				{ExpectedWorth: true, Worth: true, Score: 70},
+				{ExpectedWorth: true, Worth: false, Score: 30, ExpectedScore: intPtr(60)},
+				{ExpectedWorth: false, Worth: true, Score: 60},
+				{ExpectedWorth: false, Worth: false, Score: 10, ExpectedScore: intPtr(5)},
+				{ExpectedWorth: false, Worth: false, Score: 0},
+				// 调用失败的文章不计入指标
+				{ExpectedWorth: true, Error: "timeout", ExpectedScore: intPtr(100)},
+			},
+			want: model.Evaluation{
+				TruePositive: 2, FalsePositive: 1, TrueNegative: 2, FalseNegative: 1, Failed: 1,
+				Precision: 2.0 / 3, Recall: 2.0 / 3, Agreement: 4.0 / 6, ScoreError: (10 + 30 + 5) / 3.0,
+			},
+		},
+		{
+			name: "all-negative",
+			items: []model.EvaluationItem{
+				{ExpectedWorth: false, Worth: false},
+				{ExpectedWorth: true, Worth: false},
+			},
+			// 没有判为值得阅读的文章时精确率为0
+			want: model.Evaluation{TrueNegative: 1, FalseNegative: 1, Agreement: 0.5},
+		},
+		{
+			name:  "all-failed",
+			items: []model.EvaluationItem{{ExpectedWorth: true, Error: "401"}},
+			want:  model.Evaluation{Failed: 1},
+		},
+		{
+			name: "empty",
+			want: model.Evaluation{},
+		},
+	}
+
+	closeTo := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重复计算时先清空上次的结果
+			got := model.Evaluation{TruePositive: 9, Failed: 9, ScoreError: 9}
+			computeMetrics(&got, tt.items)
+
+			w := tt.want
+			if got.TruePositive != w.TruePositive || got.FalsePositive != w.FalsePositive ||
+				got.TrueNegative != w.TrueNegative || got.FalseNegative != w.FalseNegative || got.Failed != w.Failed {
+				t.Errorf("混淆矩阵 TP=%d FP=%d TN=%d FN=%d failed=%d, want TP=%d FP=%d TN=%d FN=%d failed=%d",
+					got.TruePositive, got.FalsePositive, got.TrueNegative, got.FalseNegative, got.Failed,
+					w.TruePositive, w.FalsePositive, w.TrueNegative, w.FalseNegative, w.Failed)
+			}
+			if !closeTo(got.Precision, w.Precision) || !closeTo(got.Recall, w.Recall) ||
+				!closeTo(got.Agreement, w.Agreement) || !closeTo(got.ScoreError, w.ScoreError) {
+				t.Errorf("精确率 %.4f 召回率 %.4f 一致率 %.4f 相关度误差 %.4f, want %.4f %.4f %.4f %.4f",
+					got.Precision, got.Recall, got.Agreement, got.ScoreError,
+					w.Precision, w.Recall, w.Agreement, w.ScoreError)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultExperimentSample = 10
+	maxExperimentSample     = 50
+)
+
+// ExperimentService 提示词A/B对比: 用两个版本处理同一批已处理文章,结果并排展示。
+// 实验结果只保存在实验记录中,不修改文章
+type ExperimentService struct {
+	db        *gorm.DB
+	processor *ProcessorService
+}
+
+func NewExperimentService(db *gorm.DB, processor *ProcessorService) *ExperimentService {
+	return &ExperimentService{db: db, processor: processor}
+}
+
+// Start 创建实验并在后台运行,两个版本必须属于同一个提示词
+func (s *ExperimentService) Start(versionAID, versionBID uint, sampleSize int) (*model.PromptExperiment, error) {
+	a, err := s.processor.prompts.GetVersion(versionAID)
+	if err != nil {
+		return nil, fmt.Errorf("版本A不存在")
+	}
+	b, err := s.processor.prompts.GetVersion(versionBID)
+	if err != nil {
+		return nil, fmt.Errorf("版本B不存在")
+	}
+	if a.Key != b.Key {
+		return nil, fmt.Errorf("只能对比同一个提示词的不同版本")
+	}
+	switch a.Key {
+	case model.ConfigPromptFilter, model.ConfigPromptSummary, model.ConfigPromptCombined:
+	default:
+		return nil, fmt.Errorf("不支持对比的提示词: %s", a.Key)
+	}
+
+	if err := s.processor.usage.CheckBudget(); err != nil {
+		return nil, err
+	}
+
+	if sampleSize <= 0 {
+		sampleSize = defaultExperimentSample
+	}
+	if sampleSize > maxExperimentSample {
+		sampleSize = maxExperimentSample
+	}
+
+	// 从已有处理结果的文章中随机抽样,便于与当前结果比较
+	var articles []model.Article
+	s.db.Where("status IN ?", []model.ArticleStatus{model.StatusProcessed, model.StatusFiltered}).
+		Order("RANDOM()").Limit(sampleSize).Find(&articles)
+	if len(articles) == 0 {
+		return nil, fmt.Errorf("没有可用于对比的已处理文章")
+	}
+
+	cfg, err := s.processor.llm.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	experiment := &model.PromptExperiment{
+		Key:        a.Key,
+		VersionAID: a.ID,
+		VersionBID: b.ID,
+		SampleSize: len(articles),
+		Model:      cfg.Model,
+		Status:     model.ExperimentRunning,
+	}
+	if err := s.db.Create(experiment).Error; err != nil {
+		return nil, err
+	}
+
+	// 使用独立的 context,不受 HTTP 请求生命周期影响
+	go s.run(context.Background(), experiment, a, b, articles)
+	return experiment, nil
+}
+
+func (s *ExperimentService) run(ctx context.Context, experiment *model.PromptExperiment,
+	a, b *model.PromptVersion, articles []model.Article) {
+	log.Printf("[Processor] 开始提示词对比实验 #%d: %s v%d / v%d, %d 篇文章",
+		experiment.ID, a.Key, a.Version, b.Version, len(articles))
+
+	var runErr error
+	for i := range articles {
+		if runErr = s.processor.usage.CheckBudget(); runErr != nil {
+			break
+		}
+		for _, variant := range []struct {
+			name    string
+			version *model.PromptVersion
+		}{{"a", a}, {"b", b}} {
+			result := s.runVariant(ctx, variant.version, &articles[i])
+			result.ExperimentID = experiment.ID
+			result.Variant = variant.name
+			if err := s.db.Create(result).Error; err != nil {
+				log.Printf("[Processor] 保存实验结果失败: %v", err)
+			}
+		}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": model.ExperimentDone, "finished_at": &now}
+	if runErr != nil {
+		updates["status"] = model.ExperimentFailed
+		updates["error"] = runErr.Error()
+	}
+	if err := s.db.Model(experiment).Updates(updates).Error; err != nil {
+		log.Printf("[Processor] 保存实验状态失败: %v", err)
+	}
+	log.Printf("[Processor] 提示词对比实验 #%d 完成", experiment.ID)
+}
+
+// runVariant 用指定版本处理一篇文章,失败时把错误记录在结果中
+func (s *ExperimentService) runVariant(ctx context.Context, version *model.PromptVersion, article *model.Article) *model.PromptExperimentResult {
+	result := &model.PromptExperimentResult{ArticleID: article.ID}
+
+	cfg, err := s.processor.llm.GetConfig()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	prompt, err := s.processor.prompts.Render(version, article)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	meta := ChatMeta{ArticleID: article.ID, Stage: model.StageExperiment}
+
+	if version.Key == model.ConfigPromptSummary {
+		chunkPrompt, err := RenderPrompt(configValue(s.db, model.ConfigPromptChunkSummary), s.processor.prompts.promptData(article, nil))
+		if err == nil {
+			result.Summary, err = s.processor.summarize(ctx, cfg.Limit, meta, article, prompt, chunkPrompt)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Summary = strings.TrimSpace(result.Summary)
+		return result
+	}
+
+	schema := filterSchema
+	if version.Key == model.ConfigPromptCombined {
+		schema = combinedSchema
+	}
+	filtered, _, err := s.processor.filter(ctx, cfg, meta, article, prompt, schema)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Worth = filtered.Worth
+	result.Score = filtered.Score
+	result.Category = strings.TrimSpace(filtered.Category)
+	result.Reason = filtered.Reason
+	result.Summary = strings.TrimSpace(filtered.Summary)
+	return result
+}
+
+// List 最近的实验
+func (s *ExperimentService) List(limit int) ([]model.PromptExperiment, error) {
+	var experiments []model.PromptExperiment
+	err := s.db.Preload("VersionA").Preload("VersionB").
+		Order("id DESC").Limit(limit).Find(&experiments).Error
+	return experiments, err
+}
+
+// Get 获取实验及全部结果,结果附带文章当前的处理结果用于比较
+func (s *ExperimentService) Get(id uint) (*model.PromptExperiment, error) {
+	var experiment model.PromptExperiment
+	err := s.db.Preload("VersionA").Preload("VersionB").
+		Preload("Results", func(db *gorm.DB) *gorm.DB { return db.Order("article_id, variant") }).
+		Preload("Results.Article", selectArticleBrief).
+		First(&experiment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &experiment, nil
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+var (
+	// 类名/ID中提示正文或非正文的关键字
+	positiveHint = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	// 非正文关键字须是完整的词 (以空白、-、_ 分隔),避免误伤 canvas、metadata、thread-body 等正常类名
+	negativeHint = regexp.MustCompile(`(?i)(?:^|[\s_-])(?:comments?|meta|footer|footnotes?|sidebar|share|sharing|social|related|sponsor(?:ed)?|promo|advert\w*|ads?|banner|widgets?|nav|navbar|navigation|menu|popup|subscribe|newsletter)(?:$|[\s_-])`)
+	blankLines   = regexp.MustCompile(`\n\s*\n+`)
+	spaces       = regexp.MustCompile(`[ \t\x{00a0}]+`)
+)
+
+// 提取正文时直接移除的节点
+const noiseSelector = "script, style, noscript, iframe, form, nav, header, footer, aside, button, svg, input, select, textarea"
+
+// candidate 正文候选节点
+type candidate struct {
+	sel   *goquery.Selection
+	score float64
+}
+
+// ExtractedContent 正文提取结果
+type ExtractedContent struct {
+	HTML      string
+	Text      string
+	Canonical string // 页面声明的 rel=canonical 地址
+}
+
+type ExtractorService struct {
+	client *http.Client
+}
+
+func NewExtractorService() *ExtractorService {
+	return &ExtractorService{
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Extract 下载文章页面并提取正文
+func (s *ExtractorService) Extract(ctx context.Context, link string) (*ExtractedContent, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; go-news/1.0)")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP状态码异常: %d", resp.StatusCode)
+	}
+
+	// 限制页面大小,避免异常页面占用过多内存
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, 5<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	canonical := canonicalLink(doc, resp.Request.URL)
+	content, err := ExtractFromDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	content.Canonical = canonical
+	return content, nil
+}
+
+// canonicalLink 读取页面的 <link rel="canonical">,相对地址按页面地址解析
+func canonicalLink(doc *goquery.Document, base *url.URL) string {
+	href := strings.TrimSpace(doc.Find(`link[rel="canonical"]`).First().AttrOr("href", ""))
+	if href == "" {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
+
+// ExtractFromDocument 使用类 readability 的打分算法提取正文
+func ExtractFromDocument(doc *goquery.Document) (*ExtractedContent, error) {
+	doc.Find(noiseSelector).Remove()
+
+	// 优先使用语义化标签
+	if article := doc.Find("article"); article.Length() == 1 && textLength(article) > 500 {
+		return buildContent(article)
+	}
+
+	candidates := make(map[*html.Node]*candidate)
+
+	doc.Find("p, pre, td, blockquote").Each(func(_ int, p *goquery.Selection) {
+		text := strings.TrimSpace(p.Text())
+		if len([]rune(text)) < 25 {
+			return
+		}
+
+		// 基础分 + 逗号数 + 长度加分(每100字符1分,最多3分)
+		score := 1.0
+		score += float64(strings.Count(text, ",") + strings.Count(text, "，"))
+		score += minFloat(float64(len([]rune(text)))/100, 3)
+
+		parent := p.Parent()
+		if parent.Length() > 0 {
+			addScore(candidates, parent, score)
+		}
+		if grand := parent.Parent(); grand.Length() > 0 {
+			addScore(candidates, grand, score/2)
+		}
+	})
+
+	var best *goquery.Selection
+	var bestScore float64
+	for _, c := range candidates {
+		// 链接密度越高越可能是导航或列表
+		score := c.score * (1 - linkDensity(c.sel))
+		if best == nil || score > bestScore {
+			best = c.sel
+			bestScore = score
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("未找到正文内容")
+	}
+
+	return buildContent(best)
+}
+
+func addScore(candidates map[*html.Node]*candidate, sel *goquery.Selection, score float64) {
+	node := sel.Get(0)
+	c, ok := candidates[node]
+	if !ok {
+		c = &candidate{sel: sel, score: classWeight(sel)}
+		candidates[node] = c
+	}
+	c.score += score
+}
+
+// classWeight 根据 class 和 id 计算权重
+func classWeight(sel *goquery.Selection) float64 {
+	var weight float64
+	for _, attr := range []string{"class", "id"} {
+		value, ok := sel.Attr(attr)
+		if !ok || value == "" {
+			continue
+		}
+		if negativeHint.MatchString(value) {
+			weight -= 25
+		}
+		if positiveHint.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+func linkDensity(sel *goquery.Selection) float64 {
+	total := textLength(sel)
+	if total == 0 {
+		return 0
+	}
+	var links int
+	sel.Find("a").Each(func(_ int, a *goquery.Selection) {
+		links += textLength(a)
+	})
+	return float64(links) / float64(total)
+}
+
+func textLength(sel *goquery.Selection) int {
+	return len([]rune(strings.TrimSpace(sel.Text())))
+}
+
+func buildContent(sel *goquery.Selection) (*ExtractedContent, error) {
+	content, err := goquery.OuterHtml(sel)
+	if err != nil {
+		return nil, err
+	}
+	return &ExtractedContent{
+		HTML: content,
+		Text: cleanText(sel),
+	}, nil
+}
+
+// cleanText 按块级元素分段输出纯文本
+func cleanText(sel *goquery.Selection) string {
+	var parts []string
+	blocks := sel.Find("p, h1, h2, h3, h4, h5, h6, li, pre, blockquote")
+	if blocks.Length() == 0 {
+		parts = append(parts, sel.Text())
+	}
+	blocks.Each(func(_ int, b *goquery.Selection) {
+		// 嵌套块只取最外层,避免重复
+		if b.ParentsFilteredUntilSelection("p, li, pre, blockquote", sel).Length() > 0 {
+			return
+		}
+		parts = append(parts, b.Text())
+	})
+
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(spaces.ReplaceAllString(part, " "))
+	}
+	text := strings.Join(parts, "\n\n")
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"go-news/config"
+	"go-news/internal/model"
+)
+
+func loadFixture(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "extractor", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestClassWeight(t *testing.T) {
+	tests := []struct {
+		class string
+		want  float64
+	}{
+		// 包含非正文关键字的子串但不是完整的词
+		{"canvas", 0},
+		{"metadata", 0},
+		{"head-line", 0},
+		{"download-link", 0},
+		{"thread-body", 25},
+		{"ad-banner", -25},
+		{"nav-menu", -25},
+		{"comments", -25},
+		{"share_buttons", -25},
+		{"post meta", 0},
+		{"related-posts", 0},
+	}
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(fmt.Sprintf(`<div class=%q></div>`, tt.class)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := classWeight(doc.Find("div")); got != tt.want {
+			t.Errorf("classWeight(%q) = %v, want %v", tt.class, got, tt.want)
+		}
+	}
+}
+
+func TestExtractFromDocument(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []string
+		absent  []string
+	}{
+		{
+			fixture: "semantic.html",
+			want:    []string{"Go 1.25 is released", "Today the Go team is happy", "testing the release candidates."},
+			absent:  []string{"Home", "Related posts", "Copyright", "analytics"},
+		},
+		{
+			fixture: "scored.html",
+			want:    []string{"How do you structure large Go services?", "We have a monolith", "by something else entirely?"},
+			absent:  []string{"Front page", "Deploy your Go services", "Posted by gopher42", "Split by feature"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			content, err := ExtractFromDocument(loadFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("ExtractFromDocument() error = %v", err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(content.Text, s) {
+					t.Errorf("正文缺少 %q:\n%s", s, content.Text)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(content.Text, s) || strings.Contains(content.HTML, s) {
+					t.Errorf("正文不应包含 %q:\n%s", s, content.Text)
+				}
+			}
+			// 按段落输出纯文本
+			if !strings.Contains(content.Text, "\n\n") || strings.Contains(content.Text, "\n\n\n") {
+				t.Errorf("段落分隔异常: %q", content.Text)
+			}
+		})
+	}
+}
+
+func TestExtractFromDocumentNoContent(t *testing.T) {
+	if content, err := ExtractFromDocument(loadFixture(t, "empty.html")); err == nil {
+		t.Errorf("没有正文时应返回错误, got %q", content.Text)
+	}
+}
+
+// 全文提取失败时保留Feed中的摘要
+func TestFetchFullTextFallback(t *testing.T) {
+	page, err := os.ReadFile(filepath.Join("testdata", "extractor", "semantic.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Test</title>
+<item><title>Go 1.25 is released</title><link>%[1]s/article</link><guid>ok</guid><description>Feed summary of Go 1.25.</description></item>
+<item><title>Empty page</title><link>%[1]s/empty</link><guid>empty</guid><description>Feed summary of an empty page.</description></item>
+<item><title>Missing page</title><link>%[1]s/missing</link><guid>missing</guid><description>Feed summary of a missing page.</description></item>
+</channel></rss>`, server.URL)
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(page)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><nav><a href="/">Home</a></nav></body></html>`))
+	})
+
+	s := newTestFeedService(t, config.FetchConfig{})
+	feed := model.Feed{Name: "test", URL: server.URL + "/feed", Enabled: true, FetchFullText: true}
+	s.db.Create(&feed)
+
+	if count, err := s.FetchFeed(context.Background(), &feed); err != nil || count != 3 {
+		t.Fatalf("FetchFeed() = %d, %v, want 3 篇新文章", count, err)
+	}
+
+	var articles []model.Article
+	s.db.Order("id").Find(&articles)
+	byGUID := make(map[string]model.Article)
+	for _, a := range articles {
+		byGUID[a.GUID] = a
+	}
+
+	ok := byGUID["ok"]
+	if !strings.Contains(ok.FullText, "Today the Go team is happy") || strings.Contains(ok.FullText, "Copyright") {
+		t.Errorf("全文提取结果异常: %q", ok.FullText)
+	}
+	if ok.Link != server.URL+"/blog/go1.25" {
+		t.Errorf("Link = %q, 应使用页面声明的 canonical 地址", ok.Link)
+	}
+
+	for _, guid := range []string{"empty", "missing"} {
+		a := byGUID[guid]
+		if a.FullText != "" || a.FullHTML != "" {
+			t.Errorf("%s: 提取失败时不应保存全文, got %q", guid, a.FullText)
+		}
+		if !strings.HasPrefix(a.BodyText(), "Feed summary of") {
+			t.Errorf("%s: BodyText() = %q, 应回退到Feed摘要", guid, a.BodyText())
+		}
+	}
+}
@@ -1,62 +1,448 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/mmcdole/gofeed"
+	"go-news/config"
 	"go-news/internal/model"
 	"gorm.io/gorm"
 )
 
+const (
+	// 失败后的退避时间: feedBackoffBase * 2^(失败次数-1),最长 feedBackoffMax
+	feedBackoffBase = 15 * time.Minute
+	feedBackoffMax  = 24 * time.Hour
+	// 连续失败达到该次数后自动禁用
+	feedMaxFailures = 10
+)
+
 type FeedService struct {
-	db     *gorm.DB
-	parser *gofeed.Parser
+	db        *gorm.DB
+	client    *http.Client
+	extractor *ExtractorService
+	cluster   *ClusterService
+	config    config.FetchConfig
+
+	mu          sync.Mutex
+	hostSlots   map[string]chan struct{} // 每个域名的并发信号量
+	lastSummary *FetchSummary
+}
+
+// FeedFetchResult 单个Feed的抓取结果
+type FeedFetchResult struct {
+	FeedID      uint          `json:"feed_id"`
+	Name        string        `json:"name"`
+	NewArticles int           `json:"new_articles"`
+	Error       string        `json:"error,omitempty"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// FetchSummary 一次批量抓取的汇总
+type FetchSummary struct {
+	StartedAt   time.Time         `json:"started_at"`
+	Duration    time.Duration     `json:"duration"`
+	Feeds       int               `json:"feeds"`
+	NewArticles int               `json:"new_articles"`
+	Errors      int               `json:"errors"`
+	Results     []FeedFetchResult `json:"results"`
 }
 
-func NewFeedService(db *gorm.DB) *FeedService {
+func NewFeedService(db *gorm.DB, cfg config.FetchConfig) *FeedService {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.PerHost <= 0 {
+		cfg.PerHost = 1
+	}
 	return &FeedService{
-		db:     db,
-		parser: gofeed.NewParser(),
+		db:        db,
+		client:    &http.Client{},
+		extractor: NewExtractorService(),
+		cluster:   NewClusterService(db),
+		config:    cfg,
+		hostSlots: make(map[string]chan struct{}),
 	}
 }
 
-// FetchFeed 抓取单个Feed
+// FetchFeed 抓取单个Feed,并记录抓取结果
 func (s *FeedService) FetchFeed(ctx context.Context, feed *model.Feed) (int, error) {
-	parsed, err := s.parser.ParseURLWithContext(feed.URL, ctx)
+	release, err := s.acquireHost(ctx, feed.URL)
 	if err != nil {
 		return 0, err
 	}
+	// 超时从拿到域名槽位后开始计算,排队等待同域名其他Feed的时间不计入
+	fetchCtx := ctx
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		fetchCtx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+	count, err := s.fetch(fetchCtx, feed)
+	release()
+
+	if saveErr := s.recordResult(feed, err); saveErr != nil {
+		log.Printf("[Feed] 保存抓取状态失败 [%s]: %v", feed.Name, saveErr)
+	}
+	return count, err
+}
+
+func (s *FeedService) fetch(ctx context.Context, feed *model.Feed) (int, error) {
+	body, notModified, err := s.download(ctx, feed)
+	if err != nil {
+		return 0, err
+	}
+	if notModified {
+		return 0, nil
+	}
+
+	// 内容未变化时跳过解析
+	hash := sha256.Sum256(body)
+	contentHash := hex.EncodeToString(hash[:])
+	if contentHash == feed.ContentHash {
+		return 0, nil
+	}
+
+	parsed, err := parseFeed(body)
+	if err != nil {
+		return 0, err
+	}
+	if parsed.Language != "" {
+		feed.Language = parsed.Language
+	}
 
 	var count int
 	for _, item := range parsed.Items {
 		article := model.Article{
 			FeedID:  feed.ID,
 			Title:   item.Title,
-			Link:    item.Link,
+			GUID:    item.GUID,
+			Link:    CleanURL(item.Link),
 			Content: item.Description,
 			PubDate: s.parseTime(item),
 		}
+		article.CanonicalURL = CanonicalURL(article.Link)
 
-		// 使用Link去重
-		result := s.db.Where("link = ?", article.Link).FirstOrCreate(&article)
-		if result.RowsAffected > 0 {
-			count++
+		if existing := s.findExisting(&article); existing != nil {
+			if existing.FeedID == feed.ID {
+				if err := s.applyUpdate(ctx, feed, existing, &article); err != nil {
+					log.Printf("[Feed] 更新文章失败 [%s]: %v", existing.Link, err)
+				}
+			}
+			continue
+		}
+
+		if feed.FetchFullText {
+			s.fetchFullText(ctx, &article)
+			// rel=canonical 可能指向已存在的文章
+			if s.findExisting(&article) != nil {
+				continue
+			}
+		}
+
+		article.Language = DetectLanguage(article.Title+"\n"+article.BodyText(), feed.Language)
+
+		if err := s.db.Create(&article).Error; err != nil {
+			log.Printf("[Feed] 保存文章失败 [%s]: %v", article.Link, err)
+			continue
+		}
+		count++
+
+		if err := s.cluster.Assign(&article); err != nil {
+			log.Printf("[Feed] 文章聚类失败 [%s]: %v", article.Link, err)
 		}
 	}
 
+	feed.ContentHash = contentHash
 	return count, nil
 }
 
-// FetchAllFeeds 抓取所有启用的Feed
-func (s *FeedService) FetchAllFeeds(ctx context.Context) error {
+// findExisting 依次按 Feed+GUID、规范化链接、链接查找已存在的文章
+func (s *FeedService) findExisting(article *model.Article) *model.Article {
+	var existing model.Article
+	if article.GUID != "" &&
+		s.db.Where("feed_id = ? AND guid = ?", article.FeedID, article.GUID).Limit(1).Find(&existing).RowsAffected > 0 {
+		return &existing
+	}
+	if article.CanonicalURL != "" &&
+		s.db.Where("canonical_url = ?", article.CanonicalURL).Limit(1).Find(&existing).RowsAffected > 0 {
+		return &existing
+	}
+	if s.db.Where("link = ?", article.Link).Limit(1).Find(&existing).RowsAffected > 0 {
+		return &existing
+	}
+	return nil
+}
+
+// fetchFullText 抓取原文页面并提取正文,失败时保留Feed中的摘要;
+// 页面声明了 rel=canonical 时以其作为文章链接
+func (s *FeedService) fetchFullText(ctx context.Context, article *model.Article) {
+	content, err := s.extractor.Extract(ctx, article.Link)
+	if err != nil {
+		log.Printf("[Feed] 提取全文失败 [%s]: %v", article.Link, err)
+		return
+	}
+
+	article.FullHTML = content.HTML
+	article.FullText = content.Text
+	if content.Canonical != "" {
+		article.Link = CleanURL(content.Canonical)
+		article.CanonicalURL = CanonicalURL(content.Canonical)
+	}
+}
+
+// BackfillCanonicalURLs 为升级前的文章补全规范化链接
+func (s *FeedService) BackfillCanonicalURLs() error {
+	var articles []model.Article
+	return s.db.Select("id", "link").Where("canonical_url = '' OR canonical_url IS NULL").
+		FindInBatches(&articles, 500, func(tx *gorm.DB, batch int) error {
+			for _, article := range articles {
+				if err := tx.Model(&model.Article{}).Where("id = ?", article.ID).
+					Update("canonical_url", CanonicalURL(article.Link)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// download 使用条件请求下载Feed内容,返回内容是否未修改
+func (s *FeedService) download(ctx context.Context, feed *model.Feed) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feed.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Set("User-Agent", "go-news/1.0")
+	if feed.ETag != "" {
+		req.Header.Set("If-None-Match", feed.ETag)
+	}
+	if feed.LastModified != "" {
+		req.Header.Set("If-Modified-Since", feed.LastModified)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, true, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, false, fmt.Errorf("HTTP状态码异常: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	feed.ETag = resp.Header.Get("ETag")
+	feed.LastModified = resp.Header.Get("Last-Modified")
+	return body, false, nil
+}
+
+// recordResult 更新Feed的缓存字段和抓取健康状态
+func (s *FeedService) recordResult(feed *model.Feed, fetchErr error) error {
+	now := time.Now()
+	feed.LastFetchedAt = &now
+
+	if fetchErr == nil {
+		feed.LastSuccessAt = &now
+		feed.LastError = ""
+		feed.FailureCount = 0
+		feed.NextFetchAt = nil
+	} else {
+		feed.LastError = fetchErr.Error()
+		feed.FailureCount++
+		next := now.Add(feedBackoff(feed.FailureCount))
+		feed.NextFetchAt = &next
+		if feed.FailureCount >= feedMaxFailures && feed.Enabled {
+			feed.Enabled = false
+			log.Printf("[Feed] 连续失败 %d 次,已自动禁用 [%s]", feed.FailureCount, feed.Name)
+		}
+	}
+
+	updates := map[string]interface{}{
+		"last_fetched_at": feed.LastFetchedAt,
+		"last_success_at": feed.LastSuccessAt,
+		"last_error":      feed.LastError,
+		"failure_count":   feed.FailureCount,
+		"next_fetch_at":   feed.NextFetchAt,
+		"enabled":         feed.Enabled,
+		"language":        feed.Language,
+	}
+	// 下载成功但解析或保存失败时不更新缓存字段,否则下次返回304或内容相同,失败的条目再也不会入库
+	if fetchErr == nil {
+		updates["etag"] = feed.ETag
+		updates["last_modified"] = feed.LastModified
+		updates["content_hash"] = feed.ContentHash
+	}
+	return s.db.Model(feed).Updates(updates).Error
+}
+
+// feedBackoff 计算连续失败后的退避时间
+func feedBackoff(failures int) time.Duration {
+	delay := feedBackoffBase
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if delay >= feedBackoffMax {
+			return feedBackoffMax
+		}
+	}
+	return delay
+}
+
+// FetchAllFeeds 并发抓取所有启用且不在退避期内的Feed
+func (s *FeedService) FetchAllFeeds(ctx context.Context) (*FetchSummary, error) {
 	var feeds []model.Feed
-	s.db.Where("enabled = ?", true).Find(&feeds)
+	if err := s.db.Where("enabled = ?", true).
+		Where("next_fetch_at IS NULL OR next_fetch_at <= ?", time.Now()).
+		Find(&feeds).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &FetchSummary{
+		StartedAt: time.Now(),
+		Feeds:     len(feeds),
+		Results:   make([]FeedFetchResult, 0, len(feeds)),
+	}
+
+	jobs := make(chan model.Feed)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				result := s.fetchOne(ctx, &feed)
+
+				mu.Lock()
+				summary.Results = append(summary.Results, result)
+				summary.NewArticles += result.NewArticles
+				if result.Error != "" {
+					summary.Errors++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// 按域名交错排列,避免同一域名的Feed占满所有worker
+	for _, feed := range interleaveByHost(feeds) {
+		select {
+		case jobs <- feed:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
 
+	summary.Duration = time.Since(summary.StartedAt)
+	sort.Slice(summary.Results, func(i, j int) bool {
+		return summary.Results[i].Duration > summary.Results[j].Duration
+	})
+
+	s.mu.Lock()
+	s.lastSummary = summary
+	s.mu.Unlock()
+
+	return summary, ctx.Err()
+}
+
+// LastFetchSummary 获取最近一次批量抓取的汇总
+func (s *FeedService) LastFetchSummary() *FetchSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSummary
+}
+
+func (s *FeedService) fetchOne(ctx context.Context, feed *model.Feed) FeedFetchResult {
+	start := time.Now()
+	count, err := s.FetchFeed(ctx, feed)
+	result := FeedFetchResult{
+		FeedID:      feed.ID,
+		Name:        feed.Name,
+		NewArticles: count,
+		Duration:    time.Since(start),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		log.Printf("[Feed] 抓取失败 [%s] (连续失败 %d 次): %v", feed.Name, feed.FailureCount, err)
+	}
+	return result
+}
+
+// acquireHost 获取域名并发槽位,返回释放函数
+func (s *FeedService) acquireHost(ctx context.Context, feedURL string) (func(), error) {
+	host := hostOf(feedURL)
+
+	s.mu.Lock()
+	slots, ok := s.hostSlots[host]
+	if !ok {
+		slots = make(chan struct{}, s.config.PerHost)
+		s.hostSlots[host] = slots
+	}
+	s.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// interleaveByHost 按域名轮流排列Feed
+func interleaveByHost(feeds []model.Feed) []model.Feed {
+	groups := make(map[string][]model.Feed)
+	var hosts []string
 	for _, feed := range feeds {
-		s.FetchFeed(ctx, &feed)
+		host := hostOf(feed.URL)
+		if _, ok := groups[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		groups[host] = append(groups[host], feed)
 	}
-	return nil
+
+	result := make([]model.Feed, 0, len(feeds))
+	for len(result) < len(feeds) {
+		for _, host := range hosts {
+			if len(groups[host]) > 0 {
+				result = append(result, groups[host][0])
+				groups[host] = groups[host][1:]
+			}
+		}
+	}
+	return result
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Hostname()
+}
+
+// parseFeed 解析Feed内容,gofeed.Parser 不是并发安全的,每次新建
+func parseFeed(body []byte) (*gofeed.Feed, error) {
+	return gofeed.NewParser().Parse(bytes.NewReader(body))
 }
 
 func (s *FeedService) parseTime(item *gofeed.Item) time.Time {
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go-news/config"
+	"go-news/internal/model"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Test</title>
+<item><title>Go 1.25 released</title><link>https://example.com/go-1.25</link><guid>go-1.25</guid>
+<description>The Go team released Go 1.25 with a new garbage collector.</description></item>
+</channel></rss>`
+
+// feedServer 按请求次数返回不同的响应,并记录每次请求的 If-None-Match
+type feedServer struct {
+	mu          sync.Mutex
+	responses   []string
+	ifNoneMatch []string
+}
+
+func (f *feedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ifNoneMatch = append(f.ifNoneMatch, r.Header.Get("If-None-Match"))
+	if r.Header.Get("If-None-Match") == `"v2"` {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	body := f.responses[0]
+	if len(f.responses) > 1 {
+		f.responses = f.responses[1:]
+	}
+	w.Header().Set("ETag", `"v2"`)
+	w.Write([]byte(body))
+}
+
+func newTestFeedService(t *testing.T, cfg config.FetchConfig) *FeedService {
+	t.Helper()
+	db := newTestDB(t, &model.Feed{}, &model.Article{}, &model.Story{}, &model.ArticleRevision{}, &model.Config{}, &model.Entity{})
+	return NewFeedService(db, cfg)
+}
+
+func TestFetchFeedKeepsValidatorsOnError(t *testing.T) {
+	handler := &feedServer{responses: []string{"<html>维护中</html>", testRSS}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s := newTestFeedService(t, config.FetchConfig{})
+	feed := model.Feed{Name: "test", URL: server.URL, Enabled: true, ETag: `"v1"`}
+	s.db.Create(&feed)
+
+	// 下载成功但解析失败,不能保存新的 ETag
+	if _, err := s.FetchFeed(context.Background(), &feed); err == nil {
+		t.Fatal("解析失败时应返回错误")
+	}
+	var saved model.Feed
+	s.db.First(&saved, feed.ID)
+	if saved.ETag != `"v1"` || saved.ContentHash != "" || saved.FailureCount != 1 {
+		t.Fatalf("失败后 etag=%q content_hash=%q failure_count=%d", saved.ETag, saved.ContentHash, saved.FailureCount)
+	}
+
+	// 下次抓取仍使用旧的 ETag,重新下载并入库
+	count, err := s.FetchFeed(context.Background(), &saved)
+	if err != nil || count != 1 {
+		t.Fatalf("FetchFeed() = %d, %v, want 1 篇新文章", count, err)
+	}
+	if handler.ifNoneMatch[1] != `"v1"` {
+		t.Errorf("第二次请求 If-None-Match = %q, want \"v1\"", handler.ifNoneMatch[1])
+	}
+	s.db.First(&saved, feed.ID)
+	if saved.ETag != `"v2"` || saved.ContentHash == "" || saved.FailureCount != 0 {
+		t.Errorf("成功后 etag=%q content_hash=%q failure_count=%d", saved.ETag, saved.ContentHash, saved.FailureCount)
+	}
+}
+
+// 同一域名的Feed排队等待槽位时不应计入超时
+func TestFetchAllFeedsTimeoutExcludesHostWait(t *testing.T) {
+	var mu sync.Mutex
+	var active, maxActive int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		mu.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(testRSS))
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	s := newTestFeedService(t, config.FetchConfig{Workers: 4, PerHost: 1, Timeout: 250 * time.Millisecond})
+	for i := 0; i < 4; i++ {
+		s.db.Create(&model.Feed{Name: fmt.Sprintf("feed-%d", i), URL: fmt.Sprintf("%s/feed/%d", server.URL, i), Enabled: true})
+	}
+
+	summary, err := s.FetchAllFeeds(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Errors != 0 {
+		for _, r := range summary.Results {
+			if r.Error != "" {
+				t.Errorf("%s: %s", r.Name, r.Error)
+			}
+		}
+	}
+	if maxActive != 1 {
+		t.Errorf("同一域名最大并发 %d, want 1", maxActive)
+	}
+	var failed int64
+	s.db.Model(&model.Feed{}).Where("failure_count > 0").Count(&failed)
+	if failed != 0 {
+		t.Errorf("%d 个Feed被记为失败", failed)
+	}
+}
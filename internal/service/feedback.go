@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	// 从最近的多少条反馈中轮换选取示例
+	fewShotPool = 50
+	// 分歧报告中最多列出的文章数
+	maxDisagreements = 50
+)
+
+// FeedbackService 管理用户对文章的赞/踩,生成筛选提示词的示例,统计 LLM 与用户的分歧
+type FeedbackService struct {
+	db *gorm.DB
+}
+
+func NewFeedbackService(db *gorm.DB) *FeedbackService {
+	return &FeedbackService{db: db}
+}
+
+// Vote 记录或修改用户对文章的反馈
+func (s *FeedbackService) Vote(articleID uint, vote int) (*model.ArticleVote, error) {
+	if vote != model.VoteUp && vote != model.VoteDown {
+		return nil, fmt.Errorf("vote 只能为 1 或 -1")
+	}
+
+	var article model.Article
+	if err := s.db.Select("id").First(&article, articleID).Error; err != nil {
+		return nil, fmt.Errorf("文章不存在")
+	}
+
+	record := model.ArticleVote{ArticleID: articleID}
+	err := s.db.Where("article_id = ?", articleID).
+		Assign(map[string]interface{}{"vote": vote}).
+		FirstOrCreate(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Unvote 撤销用户对文章的反馈
+func (s *FeedbackService) Unvote(articleID uint) error {
+	return s.db.Where("article_id = ?", articleID).Delete(&model.ArticleVote{}).Error
+}
+
+// FewShot 从最近的反馈中随机选取 count 条示例 (赞和踩尽量各半),
+// 生成附加到筛选提示词后的文本。没有可用反馈时返回空字符串
+func (s *FeedbackService) FewShot(count int, excludeArticleID uint) string {
+	if count <= 0 {
+		return ""
+	}
+
+	var votes []model.ArticleVote
+	s.db.Where("article_id <> ?", excludeArticleID).
+		Preload("Article", func(db *gorm.DB) *gorm.DB { return db.Select("id", "feed_id", "title") }).
+		Preload("Article.Feed", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Order("updated_at DESC").Limit(fewShotPool).Find(&votes)
+
+	var ups, downs []model.ArticleVote
+	for _, vote := range votes {
+		if vote.Article == nil {
+			continue
+		}
+		if vote.Vote == model.VoteUp {
+			ups = append(ups, vote)
+		} else {
+			downs = append(downs, vote)
+		}
+	}
+	rand.Shuffle(len(ups), func(i, j int) { ups[i], ups[j] = ups[j], ups[i] })
+	rand.Shuffle(len(downs), func(i, j int) { downs[i], downs[j] = downs[j], downs[i] })
+
+	// 一方不足时由另一方补齐
+	upCount := min(len(ups), max(count/2, count-len(downs)))
+	downCount := min(len(downs), count-upCount)
+	examples := append(ups[:upCount], downs[:downCount]...)
+	if len(examples) == 0 {
+		return ""
+	}
+	rand.Shuffle(len(examples), func(i, j int) { examples[i], examples[j] = examples[j], examples[i] })
+
+	var b strings.Builder
+	b.WriteString("\n\n以下是用户最近对其他文章的反馈,请参考用户的偏好进行判断:\n")
+	for _, example := range examples {
+		label := "不值得阅读"
+		if example.Vote == model.VoteUp {
+			label = "值得阅读"
+		}
+		fmt.Fprintf(&b, "- %s: %s", label, example.Article.Title)
+		if example.Article.Feed.Name != "" {
+			fmt.Fprintf(&b, " (%s)", example.Article.Feed.Name)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// FeedbackReport LLM 筛选结论与用户反馈的分歧统计,只统计已处理和已过滤的文章
+type FeedbackReport struct {
+	Total         int                 `json:"total"`
+	Agree         int                 `json:"agree"`
+	Missed        int                 `json:"missed"`   // 用户赞但被过滤
+	Unwanted      int                 `json:"unwanted"` // 用户踩但保留
+	Disagreement  float64             `json:"disagreement"`
+	Disagreements []model.ArticleVote `json:"disagreements"` // 最近存在分歧的反馈
+}
+
+// Report 统计 LLM 的筛选结论与用户反馈的分歧
+func (s *FeedbackService) Report() (*FeedbackReport, error) {
+	var rows []struct {
+		Vote   int
+		Status model.ArticleStatus
+		Count  int
+	}
+	err := s.db.Model(&model.ArticleVote{}).
+		Select("article_votes.vote, articles.status, COUNT(*) AS count").
+		Joins("JOIN articles ON articles.id = article_votes.article_id").
+		Where("articles.status IN ?", []model.ArticleStatus{model.StatusProcessed, model.StatusFiltered}).
+		Group("article_votes.vote, articles.status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FeedbackReport{Disagreements: []model.ArticleVote{}}
+	for _, row := range rows {
+		report.Total += row.Count
+		switch {
+		case row.Vote == model.VoteUp && row.Status == model.StatusFiltered:
+			report.Missed += row.Count
+		case row.Vote == model.VoteDown && row.Status == model.StatusProcessed:
+			report.Unwanted += row.Count
+		default:
+			report.Agree += row.Count
+		}
+	}
+	report.Disagreement = ratio(report.Missed+report.Unwanted, report.Total)
+
+	err = s.db.Joins("JOIN articles ON articles.id = article_votes.article_id").
+		Where("(article_votes.vote = ? AND articles.status = ?) OR (article_votes.vote = ? AND articles.status = ?)",
+			model.VoteUp, model.StatusFiltered, model.VoteDown, model.StatusProcessed).
+		Preload("Article", selectArticleBrief).
+		Order("article_votes.updated_at DESC").Limit(maxDisagreements).
+		Find(&report.Disagreements).Error
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+// languageSample 检测语言时最多检查的字符数
+const languageSample = 2000
+
+// languageNames 常见语言代码对应的名称,用于提示词模板
+var languageNames = map[string]string{
+	"zh": "中文",
+	"en": "英文",
+	"ja": "日文",
+	"ko": "韩文",
+	"ru": "俄文",
+	"de": "德文",
+	"fr": "法文",
+	"es": "西班牙文",
+}
+
+// LanguageName 语言代码对应的名称,未知的代码原样返回
+func LanguageName(code string) string {
+	if name, ok := languageNames[NormalizeLanguage(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// TargetLanguage 配置的目标语言代码,未配置时为中文
+func TargetLanguage(db *gorm.DB) string {
+	if code := NormalizeLanguage(configValue(db, model.ConfigTargetLanguage)); code != "" {
+		return code
+	}
+	return "zh"
+}
+
+// NormalizeLanguage 将 zh-CN、en_US 等语言标记规范为小写的主语言代码
+func NormalizeLanguage(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	return code
+}
+
+// DetectLanguage 按文字系统检测文章语言,返回语言代码。
+// 拉丁字母无法区分具体语言,此时使用订阅源声明的语言 (hint),未声明时视为英文;
+// 文本中没有可识别的文字时返回空字符串
+func DetectLanguage(text, hint string) string {
+	var han, kana, hangul, cyrillic, latin int
+	n := 0
+	for _, r := range stripTags(text) {
+		if n >= languageSample {
+			break
+		}
+		n++
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	hint = NormalizeLanguage(hint)
+	// 中日韩文章常夹杂英文产品名,而其他语言的文章很少出现中日韩文字,
+	// 因此中日韩文字占一成以上即视为中日韩语言
+	cjk := han + kana + hangul
+	total := cjk + cyrillic + latin
+	switch {
+	case total == 0:
+		return ""
+	case cjk*10 >= total:
+		if kana*5 > han {
+			return "ja"
+		}
+		if hangul > han {
+			return "ko"
+		}
+		return "zh"
+	case cyrillic > latin:
+		return "ru"
+	case hint != "" && hint != "zh" && hint != "ja" && hint != "ko" && hint != "ru":
+		return hint
+	default:
+		return "en"
+	}
+}
@@ -1,20 +1,32 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
+	"sync"
+	"time"
 
 	"go-news/internal/model"
 	"gorm.io/gorm"
 )
 
+const (
+	llmDefaultTimeout    = 120 * time.Second
+	llmDefaultMaxRetries = 3
+)
+
 type LLMService struct {
-	db     *gorm.DB
-	client *http.Client
+	db      *gorm.DB
+	client  *http.Client
+	limiter *RateLimiter // 所有处理协程共享
+	usage   *UsageService
+	calls   *CallLogService
+
+	// 拒绝过原生结构化输出的接口和模型,之后直接依靠提示词要求返回JSON
+	schemaRejected sync.Map
 }
 
 type LLMConfig struct {
@@ -22,66 +34,33 @@ type LLMConfig struct {
 	ApiURL   string
 	ApiKey   string
 	Model    string
-}
-
-// OpenAI 格式
-type ChatRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-}
-
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type ChatResponse struct {
-	Choices []struct {
-		Message Message `json:"message"`
-	} `json:"choices"`
-}
 
-// Google AI 格式
-type GoogleChatRequest struct {
-	Contents []struct {
-		Role  string `json:"role"`
-		Parts []struct {
-			Text string `json:"text"`
-		} `json:"parts"`
-	} `json:"contents"`
-	SystemInstruction *struct {
-		Parts []struct {
-			Text string `json:"text"`
-		} `json:"parts"`
-	} `json:"systemInstruction,omitempty"`
-}
-
-type GoogleChatResponse struct {
-	Candidates []struct {
-		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		} `json:"content"`
-	} `json:"candidates"`
-}
+	Timeout           time.Duration
+	MaxRetries        int
+	RequestsPerMinute int
+	TokensPerMinute   int
+	StructuredOutput  bool // 使用提供商原生的结构化输出
 
-type ModelsResponse struct {
-	Data []struct {
-		ID      string `json:"id"`
-		Object  string `json:"object"`
-		Created int64  `json:"created"`
-		OwnedBy string `json:"owned_by"`
-	} `json:"data"`
+	Limit ModelLimit // 当前模型的上下文限制
 }
 
 func NewLLMService(db *gorm.DB) *LLMService {
 	return &LLMService{
-		db:     db,
-		client: &http.Client{},
+		db:      db,
+		client:  &http.Client{},
+		limiter: NewRateLimiter(),
+		usage:   NewUsageService(db),
+		calls:   NewCallLogService(db),
 	}
 }
 
+// ChatMeta 调用来源,用于用量统计
+type ChatMeta struct {
+	ArticleID uint
+	Stage     string // model.StageFilter / model.StageSummary
+	Model     string // 覆盖配置的模型,用于评估,为空时使用配置
+}
+
 // GetConfig 获取LLM配置
 func (s *LLMService) GetConfig() (*LLMConfig, error) {
 	configs := make(map[string]string)
@@ -92,145 +71,178 @@ func (s *LLMService) GetConfig() (*LLMConfig, error) {
 		configs[item.Key] = item.Value
 	}
 
+	timeout := time.Duration(parseIntConfig(configs[model.ConfigLLMTimeout], 0)) * time.Second
+	if timeout == 0 {
+		timeout = llmDefaultTimeout
+	}
+
 	return &LLMConfig{
 		Provider: configs[model.ConfigLLMProvider],
 		ApiURL:   configs[model.ConfigLLMApiURL],
 		ApiKey:   configs[model.ConfigLLMApiKey],
 		Model:    configs[model.ConfigLLMModel],
+
+		Timeout:           timeout,
+		MaxRetries:        parseIntConfig(configs[model.ConfigLLMMaxRetries], llmDefaultMaxRetries),
+		RequestsPerMinute: parseIntConfig(configs[model.ConfigLLMRequestsPerMinute], 0),
+		TokensPerMinute:   parseIntConfig(configs[model.ConfigLLMTokensPerMinute], 0),
+		StructuredOutput:  configs[model.ConfigLLMStructuredOutput] != "false",
+
+		Limit: modelLimitFor(configs[model.ConfigLLMModelLimits], configs[model.ConfigLLMModel]),
 	}, nil
 }
 
-// Chat 调用LLM
-func (s *LLMService) Chat(ctx context.Context, prompt, content string) (string, error) {
+// ConfigForModel 获取LLM配置,modelName 不为空时替换模型并按新模型计算上下文限制
+func (s *LLMService) ConfigForModel(modelName string) (*LLMConfig, error) {
 	cfg, err := s.GetConfig()
-	if err != nil {
-		return "", err
+	if err != nil || modelName == "" {
+		return cfg, err
 	}
 
-	// 根据 provider 选择不同的实现
-	switch cfg.Provider {
-	case "google":
-		return s.chatGoogle(ctx, cfg, prompt, content)
-	default:
-		// openai, ollama 等使用 OpenAI 兼容格式
-		return s.chatOpenAI(ctx, cfg, prompt, content)
-	}
+	cfg.Model = modelName
+	cfg.Limit = modelLimitFor(configValue(s.db, model.ConfigLLMModelLimits), modelName)
+	return cfg, nil
 }
 
-// chatOpenAI OpenAI 兼容格式 (OpenAI, Ollama 等)
-func (s *LLMService) chatOpenAI(ctx context.Context, cfg *LLMConfig, prompt, content string) (string, error) {
-	reqBody := ChatRequest{
-		Model: cfg.Model,
-		Messages: []Message{
-			{Role: "system", Content: prompt},
-			{Role: "user", Content: content},
-		},
-	}
-
-	jsonBody, _ := json.Marshal(reqBody)
+// provider 根据配置获取当前的 Provider
+func (s *LLMService) provider(cfg *LLMConfig) (Provider, error) {
+	return NewProvider(cfg.Provider, s.client)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST",
-		cfg.ApiURL+"/chat/completions", bytes.NewBuffer(jsonBody))
+// Chat 调用LLM,返回文本
+func (s *LLMService) Chat(ctx context.Context, meta ChatMeta, prompt, content string) (string, error) {
+	cfg, err := s.ConfigForModel(meta.Model)
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+cfg.ApiKey)
-
-	resp, err := s.client.Do(req)
+	resp, err := s.send(ctx, cfg, meta, ChatRequest{System: prompt, User: content})
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("解析响应失败: %v, body: %s", err, string(body))
-	}
+	return resp.Content, nil
+}
 
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from LLM")
+// ChatJSON 调用LLM并要求按 Schema 返回 JSON,解析结果写入 out。
+// 输出无法解析或不符合 Schema 时附上错误原因重新请求一次
+func (s *LLMService) ChatJSON(ctx context.Context, meta ChatMeta, prompt, content string, schema *JSONSchema, out interface{}) error {
+	cfg, err := s.ConfigForModel(meta.Model)
+	if err != nil {
+		return err
 	}
 
-	return chatResp.Choices[0].Message.Content, nil
-}
-
-// chatGoogle Google AI Studio 格式
-func (s *LLMService) chatGoogle(ctx context.Context, cfg *LLMConfig, prompt, content string) (string, error) {
-	reqBody := GoogleChatRequest{
-		Contents: []struct {
-			Role  string `json:"role"`
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		}{
-			{
-				Role: "user",
-				Parts: []struct {
-					Text string `json:"text"`
-				}{
-					{Text: content},
-				},
-			},
-		},
+	req := ChatRequest{System: prompt, User: content}
+	key := cfg.Provider + "|" + cfg.ApiURL + "|" + cfg.Model
+	_, rejected := s.schemaRejected.Load(key)
+	if cfg.StructuredOutput && !rejected {
+		req.Schema = schema
+	} else {
+		req.System = prompt + schemaInstruction(schema)
 	}
 
-	// 添加 system instruction
-	if prompt != "" {
-		reqBody.SystemInstruction = &struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		}{
-			Parts: []struct {
-				Text string `json:"text"`
-			}{
-				{Text: prompt},
-			},
+	resp, err := s.send(ctx, cfg, meta, req)
+	if err != nil && req.Schema != nil && isBadRequest(err) {
+		// 部分 OpenAI 兼容接口 (DeepSeek、vLLM 等) 不支持 json_schema,返回400,
+		// 改为在提示词中要求返回JSON重新请求一次
+		log.Printf("[LLM] 接口不支持结构化输出,改为提示词约束重新请求: %v", err)
+		req.Schema = nil
+		req.System = prompt + schemaInstruction(schema)
+		if resp, err = s.send(ctx, cfg, meta, req); err == nil {
+			s.schemaRejected.Store(key, true)
 		}
 	}
+	if err != nil {
+		return err
+	}
 
-	jsonBody, _ := json.Marshal(reqBody)
-
-	// Google API 格式: /v1beta/models/{model}:generateContent?key={apiKey}
-	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s",
-		cfg.ApiURL, cfg.Model, cfg.ApiKey)
+	decodeErr := decodeStructured(resp.Content, schema, out)
+	if decodeErr == nil {
+		return nil
+	}
+	log.Printf("[LLM] 输出格式不正确,重新请求: %v", decodeErr)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	req.System += fmt.Sprintf("\n\n你上一次的输出格式不正确 (%v)。请只输出一个JSON对象,不要使用代码块或附加任何说明。", decodeErr)
+	resp, err = s.send(ctx, cfg, meta, req)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if err := decodeStructured(resp.Content, schema, out); err != nil {
+		return fmt.Errorf("LLM输出格式不正确: %v, 输出: %s", err, truncate(resp.Content, 200))
 	}
+	return nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
+// schemaInstruction 不使用原生结构化输出时,在提示词中附上输出格式要求
+func schemaInstruction(schema *JSONSchema) string {
+	if schema == nil {
+		return ""
+	}
+	data, err := json.Marshal(schema.Schema)
+	if err != nil {
+		return ""
+	}
+	return "\n\n请只输出一个符合以下 JSON Schema 的JSON对象,不要使用代码块或附加任何说明:\n" + string(data)
+}
 
-	resp, err := s.client.Do(req)
+// send 发送请求: 经过限流,遇到限流、超时和服务端错误时退避重试,记录调用和用量
+func (s *LLMService) send(ctx context.Context, cfg *LLMConfig, meta ChatMeta, req ChatRequest) (*ChatResponse, error) {
+	p, err := s.provider(cfg)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	tokens := cfg.Limit.EstimateTokens(req.System) + cfg.Limit.EstimateTokens(req.User)
+	s.limiter.SetRates(cfg.RequestsPerMinute, cfg.TokensPerMinute)
 
-	var googleResp GoogleChatResponse
-	if err := json.Unmarshal(body, &googleResp); err != nil {
-		return "", fmt.Errorf("解析响应失败: %v, body: %s", err, string(body))
+	for attempt := 0; ; attempt++ {
+		if err := s.limiter.Wait(ctx, tokens); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := s.chatOnce(ctx, p, cfg, req)
+		if logErr := s.calls.Record(cfg, meta, req, attempt+1, resp, err, time.Since(start)); logErr != nil {
+			log.Printf("[LLM] 保存调用记录失败: %v", logErr)
+		}
+		if err == nil {
+			s.recordUsage(cfg, meta, tokens, resp)
+			return resp, nil
+		}
+		if attempt >= cfg.MaxRetries || !isRetryable(ctx, err) {
+			return nil, err
+		}
+
+		delay := retryDelay(err, attempt)
+		log.Printf("[LLM] 请求失败,%v 后第 %d 次重试: %v", delay.Round(time.Second), attempt+1, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
 	}
+}
 
-	if len(googleResp.Candidates) == 0 || len(googleResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no response from Google AI")
+// recordUsage 保存用量,提供商未返回用量时按文本长度估算
+func (s *LLMService) recordUsage(cfg *LLMConfig, meta ChatMeta, promptTokens int, resp *ChatResponse) {
+	usage := resp.Usage
+	estimated := usage.PromptTokens == 0 && usage.CompletionTokens == 0
+	if estimated {
+		usage = TokenUsage{PromptTokens: promptTokens, CompletionTokens: cfg.Limit.EstimateTokens(resp.Content)}
 	}
 
-	return googleResp.Candidates[0].Content.Parts[0].Text, nil
+	if err := s.usage.Record(cfg, meta, usage, estimated); err != nil {
+		log.Printf("[LLM] 保存用量失败: %v", err)
+	}
 }
 
-// GetPrompt 获取提示词
-func (s *LLMService) GetPrompt(key string) string {
-	var config model.Config
-	s.db.Where("key = ?", key).First(&config)
-	return config.Value
+// chatOnce 发送单次请求,超过配置的超时时间后取消
+func (s *LLMService) chatOnce(ctx context.Context, p Provider, cfg *LLMConfig, req ChatRequest) (*ChatResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	return p.Chat(ctx, cfg, req)
 }
 
 // GetModels 获取可用模型列表
@@ -240,37 +252,14 @@ func (s *LLMService) GetModels(ctx context.Context) ([]string, error) {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET",
-		cfg.ApiURL+"/models", nil)
+	p, err := s.provider(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+cfg.ApiKey)
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API返回错误: %d", resp.StatusCode)
-	}
-
-	body, _ := io.ReadAll(resp.Body)
-
-	var modelsResp ModelsResponse
-	if err := json.Unmarshal(body, &modelsResp); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
-	}
-
-	models := make([]string, 0, len(modelsResp.Data))
-	for _, m := range modelsResp.Data {
-		models = append(models, m.ID)
-	}
-
-	return models, nil
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	return p.ListModels(ctx, cfg)
 }
 
 // TestConnection 测试LLM连接
@@ -284,13 +273,16 @@ func (s *LLMService) TestConnection(ctx context.Context) (string, error) {
 	if cfg.ApiURL == "" {
 		return "", fmt.Errorf("API地址未配置")
 	}
-	if cfg.ApiKey == "" {
-		return "", fmt.Errorf("API密钥未配置")
-	}
 	if cfg.Model == "" {
 		return "", fmt.Errorf("模型未配置")
 	}
 
-	// 使用 Chat 方法进行测试,会自动选择正确的 provider
-	return s.Chat(ctx, "", "Hi")
+	p, err := s.provider(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	return p.TestConnection(ctx, cfg)
 }
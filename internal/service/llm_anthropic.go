@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 4096
+)
+
+// AnthropicProvider Anthropic Messages API
+type AnthropicProvider struct {
+	client *http.Client
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicChatRequest struct {
+	Model      string               `json:"model"`
+	MaxTokens  int                  `json:"max_tokens"`
+	System     string               `json:"system,omitempty"`
+	Messages   []anthropicMessage   `json:"messages"`
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// anthropicTool 结构化输出通过强制调用工具实现,工具参数即为结果
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicChatResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+type anthropicModelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+func (p *AnthropicProvider) setHeaders(req *http.Request, cfg *LLMConfig) {
+	req.Header.Set("x-api-key", cfg.ApiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+}
+
+func (p *AnthropicProvider) Chat(ctx context.Context, cfg *LLMConfig, req ChatRequest) (*ChatResponse, error) {
+	body := anthropicChatRequest{
+		Model:     cfg.Model,
+		MaxTokens: anthropicMaxTokens,
+		System:    req.System,
+		Messages:  []anthropicMessage{{Role: "user", Content: req.User}},
+	}
+	if req.Schema != nil {
+		body.Tools = []anthropicTool{{
+			Name:        req.Schema.Name,
+			Description: "按要求的格式返回结果",
+			InputSchema: req.Schema.Schema,
+		}}
+		body.ToolChoice = &anthropicToolChoice{Type: "tool", Name: req.Schema.Name}
+	}
+
+	httpReq, err := newJSONRequest(ctx, "POST", trimBaseURL(cfg.ApiURL, "/v1")+"/v1/messages", body)
+	if err != nil {
+		return nil, err
+	}
+	p.setHeaders(httpReq, cfg)
+
+	var resp anthropicChatResponse
+	if err := doJSON(p.client, httpReq, &resp); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" {
+			// 结构化输出: 工具参数即为结果
+			text.Reset()
+			text.Write(block.Input)
+			break
+		}
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("no response from Anthropic")
+	}
+
+	return &ChatResponse{
+		Content: text.String(),
+		Usage:   TokenUsage{PromptTokens: resp.Usage.InputTokens, CompletionTokens: resp.Usage.OutputTokens},
+	}, nil
+}
+
+func (p *AnthropicProvider) ListModels(ctx context.Context, cfg *LLMConfig) ([]string, error) {
+	var models []string
+	afterID := ""
+
+	for {
+		endpoint := trimBaseURL(cfg.ApiURL, "/v1") + "/v1/models?limit=1000"
+		if afterID != "" {
+			endpoint += "&after_id=" + afterID
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.setHeaders(req, cfg)
+
+		var resp anthropicModelsResponse
+		if err := doJSON(p.client, req, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, m := range resp.Data {
+			models = append(models, m.ID)
+		}
+
+		if !resp.HasMore || resp.LastID == "" {
+			return models, nil
+		}
+		afterID = resp.LastID
+	}
+}
+
+func (p *AnthropicProvider) TestConnection(ctx context.Context, cfg *LLMConfig) (string, error) {
+	return testChat(ctx, p, cfg, true)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"time"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	// 记录的输入正文长度上限(字符)
+	llmCallInputLimit = 8000
+	// 记录的响应长度上限(字符)
+	llmCallResponseLimit = 20000
+
+	llmCallDefaultPageSize = 50
+	llmCallMaxPageSize     = 200
+)
+
+// LLMCallFilter 调用记录的筛选条件
+type LLMCallFilter struct {
+	ArticleID uint
+	Stage     string
+	Status    string
+	Model     string
+	Page      int
+	PageSize  int
+}
+
+type CallLogService struct {
+	db *gorm.DB
+}
+
+func NewCallLogService(db *gorm.DB) *CallLogService {
+	return &CallLogService{db: db}
+}
+
+// Record 保存一次请求及其结果
+func (s *CallLogService) Record(cfg *LLMConfig, meta ChatMeta, req ChatRequest, attempt int,
+	resp *ChatResponse, callErr error, latency time.Duration) error {
+	call := model.LLMCall{
+		Stage:     meta.Stage,
+		Provider:  cfg.Provider,
+		Model:     cfg.Model,
+		Attempt:   attempt,
+		Prompt:    req.System,
+		Input:     truncate(req.User, llmCallInputLimit),
+		LatencyMs: latency.Milliseconds(),
+		Status:    model.CallStatusSuccess,
+	}
+	if meta.ArticleID > 0 {
+		call.ArticleID = &meta.ArticleID
+	}
+	if resp != nil {
+		call.Response = truncate(resp.Content, llmCallResponseLimit)
+	}
+	if callErr != nil {
+		call.Status = model.CallStatusError
+		call.Error = callErr.Error()
+	}
+
+	return s.db.Create(&call).Error
+}
+
+// List 按条件分页查询,列表不返回提示词和正文
+func (s *CallLogService) List(filter LLMCallFilter) ([]model.LLMCall, int64, error) {
+	query := s.db.Model(&model.LLMCall{})
+	if filter.ArticleID > 0 {
+		query = query.Where("article_id = ?", filter.ArticleID)
+	}
+	if filter.Stage != "" {
+		query = query.Where("stage = ?", filter.Stage)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Model != "" {
+		query = query.Where("model = ?", filter.Model)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = llmCallDefaultPageSize
+	}
+	if pageSize > llmCallMaxPageSize {
+		pageSize = llmCallMaxPageSize
+	}
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+
+	var calls []model.LLMCall
+	err := query.Omit("prompt", "input", "response").
+		Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&calls).Error
+	return calls, total, err
+}
+
+// ForArticle 某篇文章的全部调用,按时间顺序
+func (s *CallLogService) ForArticle(articleID uint) ([]model.LLMCall, error) {
+	var calls []model.LLMCall
+	err := s.db.Where("article_id = ?", articleID).Order("id").Find(&calls).Error
+	return calls, err
+}
+
+// Get 获取单条完整记录
+func (s *CallLogService) Get(id uint) (*model.LLMCall, error) {
+	var call model.LLMCall
+	if err := s.db.First(&call, id).Error; err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+// Prune 按保留天数和最大条数清理旧记录
+func (s *CallLogService) Prune() (int64, error) {
+	var removed int64
+
+	if days := parseIntConfig(configValue(s.db, model.ConfigLLMCallRetentionDays), 0); days > 0 {
+		result := s.db.Where("created_at < ?", time.Now().AddDate(0, 0, -days)).Delete(&model.LLMCall{})
+		if result.Error != nil {
+			return removed, result.Error
+		}
+		removed += result.RowsAffected
+	}
+
+	if max := parseIntConfig(configValue(s.db, model.ConfigLLMCallMaxRecords), 0); max > 0 {
+		// 找到第 max 新的记录,删除比它更早的
+		var cutoff model.LLMCall
+		s.db.Select("id").Order("id DESC").Offset(max - 1).Limit(1).Find(&cutoff)
+		if cutoff.ID > 0 {
+			result := s.db.Where("id < ?", cutoff.ID).Delete(&model.LLMCall{})
+			if result.Error != nil {
+				return removed, result.Error
+			}
+			removed += result.RowsAffected
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	llmRetryBase = 2 * time.Second
+	llmRetryMax  = 60 * time.Second
+)
+
+// APIError LLM接口返回的非 2xx 响应
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // 服务端通过 Retry-After 要求的等待时间
+}
+
+func (e *APIError) Error() string {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests:
+		return fmt.Sprintf("API限流 (429): %s", e.Body)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Sprintf("API认证失败 (%d): %s", e.StatusCode, e.Body)
+	}
+	if e.StatusCode >= 500 {
+		return fmt.Sprintf("API服务端错误 (%d): %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("API返回错误 (%d): %s", e.StatusCode, e.Body)
+}
+
+// Retryable 限流、超时和服务端错误可以重试
+func (e *APIError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusRequestTimeout, 529:
+		return true
+	}
+	return e.StatusCode >= 500
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       truncate(string(body), 500),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter 解析秒数或 HTTP 日期格式的 Retry-After
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// isBadRequest 请求参数被接口拒绝 (400)
+func isBadRequest(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest
+}
+
+// isRetryable 判断错误是否值得重试
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	// 单次请求超时和网络错误
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryDelay 计算第 attempt 次重试前的等待时间,优先使用 Retry-After,否则指数退避加随机抖动
+func retryDelay(err error, attempt int) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if apiErr.RetryAfter > llmRetryMax {
+			return llmRetryMax
+		}
+		return apiErr.RetryAfter
+	}
+
+	backoff := llmRetryBase << uint(attempt)
+	if backoff > llmRetryMax || backoff <= 0 {
+		backoff = llmRetryMax
+	}
+	// full jitter
+	return time.Duration(rand.Int63n(int64(backoff))) + time.Second/2
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GoogleProvider Google AI Studio (Gemini) 格式
+type GoogleProvider struct {
+	client *http.Client
+}
+
+type googlePart struct {
+	Text string `json:"text"`
+}
+
+type googleContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []googlePart `json:"parts"`
+}
+
+type googleChatRequest struct {
+	Contents          []googleContent         `json:"contents"`
+	SystemInstruction *googleContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *googleGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type googleGenerationConfig struct {
+	ResponseMimeType string                 `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]interface{} `json:"responseSchema,omitempty"`
+}
+
+type googleChatResponse struct {
+	Candidates []struct {
+		Content googleContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+type googleModelsResponse struct {
+	Models []struct {
+		Name                       string   `json:"name"`
+		SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	} `json:"models"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+func (p *GoogleProvider) Chat(ctx context.Context, cfg *LLMConfig, req ChatRequest) (*ChatResponse, error) {
+	body := googleChatRequest{
+		Contents: []googleContent{
+			{Role: "user", Parts: []googlePart{{Text: req.User}}},
+		},
+	}
+
+	// 添加 system instruction
+	if req.System != "" {
+		body.SystemInstruction = &googleContent{Parts: []googlePart{{Text: req.System}}}
+	}
+
+	if req.Schema != nil {
+		body.GenerationConfig = &googleGenerationConfig{
+			ResponseMimeType: "application/json",
+			ResponseSchema:   googleSchema(req.Schema.Schema),
+		}
+	}
+
+	// Google API 格式: /v1beta/models/{model}:generateContent?key={apiKey}
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s",
+		trimBaseURL(cfg.ApiURL, "/v1beta"), cfg.Model, url.QueryEscape(cfg.ApiKey))
+
+	httpReq, err := newJSONRequest(ctx, "POST", endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp googleChatResponse
+	if err := doJSON(p.client, httpReq, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("no response from Google AI")
+	}
+
+	return &ChatResponse{
+		Content: resp.Candidates[0].Content.Parts[0].Text,
+		Usage: TokenUsage{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+		},
+	}, nil
+}
+
+func (p *GoogleProvider) ListModels(ctx context.Context, cfg *LLMConfig) ([]string, error) {
+	var models []string
+	pageToken := ""
+
+	for {
+		endpoint := fmt.Sprintf("%s/v1beta/models?key=%s&pageSize=1000",
+			trimBaseURL(cfg.ApiURL, "/v1beta"), url.QueryEscape(cfg.ApiKey))
+		if pageToken != "" {
+			endpoint += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp googleModelsResponse
+		if err := doJSON(p.client, req, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, m := range resp.Models {
+			// 只保留支持对话生成的模型
+			for _, method := range m.SupportedGenerationMethods {
+				if method == "generateContent" {
+					models = append(models, strings.TrimPrefix(m.Name, "models/"))
+					break
+				}
+			}
+		}
+
+		if resp.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+func (p *GoogleProvider) TestConnection(ctx context.Context, cfg *LLMConfig) (string, error) {
+	return testChat(ctx, p, cfg, true)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter 客户端令牌桶限流,同时限制每分钟请求数和每分钟 token 数,
+// 由所有处理协程共享。速率为0表示不限制
+type RateLimiter struct {
+	mu sync.Mutex
+
+	requestsPerMin int
+	tokensPerMin   int
+
+	requests float64 // 当前可用请求数
+	tokens   float64 // 当前可用 token 数
+	last     time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{last: time.Now()}
+}
+
+// SetRates 更新限流速率,速率变化时重置令牌桶
+func (l *RateLimiter) SetRates(requestsPerMin, tokensPerMin int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.requestsPerMin == requestsPerMin && l.tokensPerMin == tokensPerMin {
+		return
+	}
+	l.requestsPerMin = requestsPerMin
+	l.tokensPerMin = tokensPerMin
+	l.requests = float64(requestsPerMin)
+	l.tokens = float64(tokensPerMin)
+	l.last = time.Now()
+}
+
+// Wait 阻塞直到有足够的请求和 token 配额
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	for {
+		delay := l.reserve(tokens)
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 尝试扣除配额,不足时返回需要等待的时间
+func (l *RateLimiter) reserve(tokens int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(l.last).Minutes()
+	l.last = now
+
+	if l.requestsPerMin > 0 {
+		l.requests = minFloat(l.requests+elapsed*float64(l.requestsPerMin), float64(l.requestsPerMin))
+	}
+	if l.tokensPerMin > 0 {
+		l.tokens = minFloat(l.tokens+elapsed*float64(l.tokensPerMin), float64(l.tokensPerMin))
+		// 单次请求超过桶容量时按容量计算,避免永远等待
+		if tokens > l.tokensPerMin {
+			tokens = l.tokensPerMin
+		}
+	}
+
+	var wait time.Duration
+	if l.requestsPerMin > 0 && l.requests < 1 {
+		wait = maxDuration(wait, perMinuteWait(1-l.requests, l.requestsPerMin))
+	}
+	if l.tokensPerMin > 0 && l.tokens < float64(tokens) {
+		wait = maxDuration(wait, perMinuteWait(float64(tokens)-l.tokens, l.tokensPerMin))
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	if l.requestsPerMin > 0 {
+		l.requests--
+	}
+	if l.tokensPerMin > 0 {
+		l.tokens -= float64(tokens)
+	}
+	return 0
+}
+
+// perMinuteWait 按每分钟速率补充 missing 个配额所需时间
+func perMinuteWait(missing float64, perMin int) time.Duration {
+	return time.Duration(missing / float64(perMin) * float64(time.Minute))
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
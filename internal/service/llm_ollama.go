@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"net/http"
+)
+
+// OllamaProvider Ollama 原生接口 (/api/chat)
+type OllamaProvider struct {
+	client *http.Client
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   interface{}     `json:"format,omitempty"` // JSON Schema,需要 Ollama 0.5+
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// baseURL 兼容旧配置中 OpenAI 兼容地址的 /v1 后缀
+func (p *OllamaProvider) baseURL(cfg *LLMConfig) string {
+	return trimBaseURL(cfg.ApiURL, "/v1", "/api")
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, cfg *LLMConfig, req ChatRequest) (*ChatResponse, error) {
+	body := ollamaChatRequest{Model: cfg.Model}
+	if req.System != "" {
+		body.Messages = append(body.Messages, ollamaMessage{Role: "system", Content: req.System})
+	}
+	body.Messages = append(body.Messages, ollamaMessage{Role: "user", Content: req.User})
+	if req.Schema != nil {
+		body.Format = req.Schema.Schema
+	}
+
+	httpReq, err := newJSONRequest(ctx, "POST", p.baseURL(cfg)+"/api/chat", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ollamaChatResponse
+	if err := doJSON(p.client, httpReq, &resp); err != nil {
+		return nil, err
+	}
+
+	return &ChatResponse{
+		Content: resp.Message.Content,
+		Usage:   TokenUsage{PromptTokens: resp.PromptEvalCount, CompletionTokens: resp.EvalCount},
+	}, nil
+}
+
+func (p *OllamaProvider) ListModels(ctx context.Context, cfg *LLMConfig) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL(cfg)+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ollamaTagsResponse
+	if err := doJSON(p.client, req, &resp); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
+
+// TestConnection 本地 Ollama 不需要API密钥
+func (p *OllamaProvider) TestConnection(ctx context.Context, cfg *LLMConfig) (string, error) {
+	return testChat(ctx, p, cfg, false)
+}
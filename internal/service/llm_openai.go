@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// OpenAIProvider OpenAI 兼容格式 (OpenAI、DeepSeek、vLLM 等)
+type OpenAIProvider struct {
+	client *http.Client
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+type openAIModelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, cfg *LLMConfig, req ChatRequest) (*ChatResponse, error) {
+	body := openAIChatRequest{Model: cfg.Model}
+	if req.System != "" {
+		body.Messages = append(body.Messages, openAIMessage{Role: "system", Content: req.System})
+	}
+	body.Messages = append(body.Messages, openAIMessage{Role: "user", Content: req.User})
+	if req.Schema != nil {
+		body.ResponseFormat = &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: req.Schema.Name, Strict: true, Schema: req.Schema.Schema},
+		}
+	}
+
+	httpReq, err := newJSONRequest(ctx, "POST", trimBaseURL(cfg.ApiURL)+"/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+cfg.ApiKey)
+
+	var resp openAIChatResponse
+	if err := doJSON(p.client, httpReq, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from LLM")
+	}
+
+	return &ChatResponse{
+		Content: resp.Choices[0].Message.Content,
+		Usage:   TokenUsage{PromptTokens: resp.Usage.PromptTokens, CompletionTokens: resp.Usage.CompletionTokens},
+	}, nil
+}
+
+func (p *OpenAIProvider) ListModels(ctx context.Context, cfg *LLMConfig) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", trimBaseURL(cfg.ApiURL)+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.ApiKey)
+
+	var resp openAIModelsResponse
+	if err := doJSON(p.client, req, &resp); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+func (p *OpenAIProvider) TestConnection(ctx context.Context, cfg *LLMConfig) (string, error) {
+	return testChat(ctx, p, cfg, true)
+}
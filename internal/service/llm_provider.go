@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChatRequest 与提供商无关的对话请求
+type ChatRequest struct {
+	System string      // 系统提示词
+	User   string      // 用户输入
+	Schema *JSONSchema // 要求按 Schema 返回 JSON,为空时返回普通文本
+}
+
+// ChatResponse 与提供商无关的对话结果
+type ChatResponse struct {
+	Content string
+	Usage   TokenUsage
+}
+
+// TokenUsage 单次调用消耗的 token 数,提供商未返回时为0
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Provider LLM 提供商的统一接口
+type Provider interface {
+	// Chat 发送一轮对话
+	Chat(ctx context.Context, cfg *LLMConfig, req ChatRequest) (*ChatResponse, error)
+	// ListModels 获取可用模型列表
+	ListModels(ctx context.Context, cfg *LLMConfig) ([]string, error)
+	// TestConnection 校验配置并发送测试消息
+	TestConnection(ctx context.Context, cfg *LLMConfig) (string, error)
+}
+
+// ProviderFactory 使用共享的 http.Client 创建 Provider
+type ProviderFactory func(client *http.Client) Provider
+
+// 未配置提供商时使用的默认值
+const defaultProvider = "openai"
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]ProviderFactory)
+)
+
+// RegisterProvider 注册 Provider,重复注册时覆盖
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+// NewProvider 按名称创建 Provider。未知的名称与旧版本一样按 OpenAI 兼容接口处理
+func NewProvider(name string, client *http.Client) (Provider, error) {
+	if name == "" {
+		name = defaultProvider
+	}
+
+	providersMu.RLock()
+	factory, ok := providers[name]
+	if !ok {
+		log.Printf("[LLM] 未知的LLM提供商 %s,按 %s 处理", name, defaultProvider)
+		factory, ok = providers[defaultProvider]
+	}
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的LLM提供商: %s", name)
+	}
+	return factory(client), nil
+}
+
+// ProviderNames 已注册的提供商名称
+func ProviderNames() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterProvider("openai", func(client *http.Client) Provider { return &OpenAIProvider{client: client} })
+	RegisterProvider("google", func(client *http.Client) Provider { return &GoogleProvider{client: client} })
+	RegisterProvider("anthropic", func(client *http.Client) Provider { return &AnthropicProvider{client: client} })
+	RegisterProvider("ollama", func(client *http.Client) Provider { return &OllamaProvider{client: client} })
+}
+
+// doJSON 发送请求并解析 JSON 响应,非 2xx 状态码返回错误
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newAPIError(resp, body)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("解析响应失败: %v, body: %s", err, truncate(string(body), 500))
+	}
+	return nil
+}
+
+// newJSONRequest 创建带 JSON 请求体的请求
+func newJSONRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// testChat 发送一条测试消息,requireKey 表示该提供商必须配置API密钥
+func testChat(ctx context.Context, p Provider, cfg *LLMConfig, requireKey bool) (string, error) {
+	if requireKey && cfg.ApiKey == "" {
+		return "", fmt.Errorf("API密钥未配置")
+	}
+	resp, err := p.Chat(ctx, cfg, ChatRequest{User: "Hi"})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+// trimBaseURL 去掉末尾斜杠和指定的版本后缀,兼容用户填写带版本号的地址
+func trimBaseURL(url string, suffixes ...string) string {
+	url = strings.TrimRight(url, "/")
+	for _, suffix := range suffixes {
+		url = strings.TrimSuffix(url, suffix)
+	}
+	return url
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// capturedRequest 测试服务器收到的请求
+type capturedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// newProviderServer 启动返回固定响应的测试服务器,并记录最近一次请求
+func newProviderServer(t *testing.T, status int, response string) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	captured := &capturedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.Method = r.Method
+		captured.Path = r.URL.Path
+		captured.Query = r.URL.Query()
+		captured.Header = r.Header.Clone()
+		captured.Body = nil
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			if err := json.Unmarshal(data, &captured.Body); err != nil {
+				t.Errorf("请求体不是JSON: %v, body: %s", err, data)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, captured
+}
+
+// jsonPath 按路径读取请求体中的值,数组下标使用 int
+func jsonPath(body interface{}, path ...interface{}) interface{} {
+	for _, key := range path {
+		switch k := key.(type) {
+		case string:
+			m, ok := body.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			body = m[k]
+		case int:
+			a, ok := body.([]interface{})
+			if !ok || k >= len(a) {
+				return nil
+			}
+			body = a[k]
+		}
+	}
+	return body
+}
+
+func expectJSON(t *testing.T, body interface{}, want interface{}, path ...interface{}) {
+	t.Helper()
+	if got := jsonPath(body, path...); !reflect.DeepEqual(got, want) {
+		t.Errorf("请求体 %v = %#v, want %#v", path, got, want)
+	}
+}
+
+var testSchema = &JSONSchema{
+	Name:   "test_result",
+	Schema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"ok": map[string]interface{}{"type": "boolean"}}},
+}
+
+func TestOpenAIProviderChat(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK,
+		`{"choices":[{"message":{"role":"assistant","content":"你好"}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`)
+	cfg := &LLMConfig{ApiURL: server.URL + "/v1/", ApiKey: "sk-test", Model: "gpt-4o-mini"}
+
+	resp, err := (&OpenAIProvider{client: server.Client()}).Chat(context.Background(), cfg,
+		ChatRequest{System: "系统", User: "用户", Schema: testSchema})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Method != "POST" || req.Path != "/v1/chat/completions" {
+		t.Errorf("请求 %s %s", req.Method, req.Path)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", got)
+	}
+	expectJSON(t, req.Body, "gpt-4o-mini", "model")
+	expectJSON(t, req.Body, "system", "messages", 0, "role")
+	expectJSON(t, req.Body, "系统", "messages", 0, "content")
+	expectJSON(t, req.Body, "user", "messages", 1, "role")
+	expectJSON(t, req.Body, "用户", "messages", 1, "content")
+	expectJSON(t, req.Body, "json_schema", "response_format", "type")
+	expectJSON(t, req.Body, "test_result", "response_format", "json_schema", "name")
+	expectJSON(t, req.Body, true, "response_format", "json_schema", "strict")
+
+	want := &ChatResponse{Content: "你好", Usage: TokenUsage{PromptTokens: 12, CompletionTokens: 3}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("Chat() = %+v, want %+v", resp, want)
+	}
+}
+
+func TestOpenAIProviderChatWithoutSystem(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK, `{"choices":[{"message":{"content":"ok"}}]}`)
+	cfg := &LLMConfig{ApiURL: server.URL, Model: "m"}
+
+	if _, err := (&OpenAIProvider{client: server.Client()}).Chat(context.Background(), cfg, ChatRequest{User: "Hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(jsonPath(req.Body, "messages").([]interface{})); n != 1 {
+		t.Errorf("消息数 = %d, want 1", n)
+	}
+	if jsonPath(req.Body, "response_format") != nil {
+		t.Errorf("未要求结构化输出时不应发送 response_format")
+	}
+}
+
+func TestOpenAIProviderListModels(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK, `{"data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`)
+	cfg := &LLMConfig{ApiURL: server.URL + "/v1", ApiKey: "sk-test"}
+
+	models, err := (&OpenAIProvider{client: server.Client()}).ListModels(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "GET" || req.Path != "/v1/models" || req.Header.Get("Authorization") != "Bearer sk-test" {
+		t.Errorf("请求 %s %s, Authorization %q", req.Method, req.Path, req.Header.Get("Authorization"))
+	}
+	if want := []string{"gpt-4o", "gpt-4o-mini"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %v, want %v", models, want)
+	}
+}
+
+func TestGoogleProviderChat(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"你好"}]}}],"usageMetadata":{"promptTokenCount":20,"candidatesTokenCount":4}}`)
+	cfg := &LLMConfig{ApiURL: server.URL + "/v1beta", ApiKey: "key&1", Model: "gemini-2.0-flash"}
+
+	resp, err := (&GoogleProvider{client: server.Client()}).Chat(context.Background(), cfg,
+		ChatRequest{System: "系统", User: "用户", Schema: testSchema})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Method != "POST" || req.Path != "/v1beta/models/gemini-2.0-flash:generateContent" {
+		t.Errorf("请求 %s %s", req.Method, req.Path)
+	}
+	if got := req.Query["key"]; !reflect.DeepEqual(got, []string{"key&1"}) {
+		t.Errorf("key = %v", got)
+	}
+	expectJSON(t, req.Body, "user", "contents", 0, "role")
+	expectJSON(t, req.Body, "用户", "contents", 0, "parts", 0, "text")
+	expectJSON(t, req.Body, "系统", "systemInstruction", "parts", 0, "text")
+	expectJSON(t, req.Body, "application/json", "generationConfig", "responseMimeType")
+	if jsonPath(req.Body, "generationConfig", "responseSchema") == nil {
+		t.Errorf("缺少 responseSchema")
+	}
+
+	want := &ChatResponse{Content: "你好", Usage: TokenUsage{PromptTokens: 20, CompletionTokens: 4}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("Chat() = %+v, want %+v", resp, want)
+	}
+}
+
+func TestGoogleProviderListModels(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK, `{"models":[
+		{"name":"models/gemini-2.0-flash","supportedGenerationMethods":["generateContent","countTokens"]},
+		{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}]}`)
+	cfg := &LLMConfig{ApiURL: server.URL, ApiKey: "key"}
+
+	models, err := (&GoogleProvider{client: server.Client()}).ListModels(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Path != "/v1beta/models" || req.Query.Get("key") != "key" {
+		t.Errorf("请求 %s, key %q", req.Path, req.Query.Get("key"))
+	}
+	if want := []string{"gemini-2.0-flash"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %v, want %v", models, want)
+	}
+}
+
+func TestAnthropicProviderChat(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK,
+		`{"content":[{"type":"text","text":"你"},{"type":"text","text":"好"}],"usage":{"input_tokens":30,"output_tokens":5}}`)
+	cfg := &LLMConfig{ApiURL: server.URL + "/v1", ApiKey: "sk-ant", Model: "claude-sonnet"}
+
+	resp, err := (&AnthropicProvider{client: server.Client()}).Chat(context.Background(), cfg,
+		ChatRequest{System: "系统", User: "用户"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Method != "POST" || req.Path != "/v1/messages" {
+		t.Errorf("请求 %s %s", req.Method, req.Path)
+	}
+	if req.Header.Get("x-api-key") != "sk-ant" || req.Header.Get("anthropic-version") != anthropicVersion {
+		t.Errorf("x-api-key %q, anthropic-version %q", req.Header.Get("x-api-key"), req.Header.Get("anthropic-version"))
+	}
+	expectJSON(t, req.Body, "claude-sonnet", "model")
+	expectJSON(t, req.Body, float64(anthropicMaxTokens), "max_tokens")
+	expectJSON(t, req.Body, "系统", "system")
+	expectJSON(t, req.Body, "user", "messages", 0, "role")
+	expectJSON(t, req.Body, "用户", "messages", 0, "content")
+	if jsonPath(req.Body, "tools") != nil {
+		t.Errorf("未要求结构化输出时不应发送 tools")
+	}
+
+	want := &ChatResponse{Content: "你好", Usage: TokenUsage{PromptTokens: 30, CompletionTokens: 5}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("Chat() = %+v, want %+v", resp, want)
+	}
+}
+
+func TestAnthropicProviderChatToolUse(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK,
+		`{"content":[{"type":"text","text":"好的"},{"type":"tool_use","name":"test_result","input":{"ok":true}}],"usage":{"input_tokens":1,"output_tokens":1}}`)
+	cfg := &LLMConfig{ApiURL: server.URL, ApiKey: "sk-ant", Model: "claude-sonnet"}
+
+	resp, err := (&AnthropicProvider{client: server.Client()}).Chat(context.Background(), cfg,
+		ChatRequest{User: "用户", Schema: testSchema})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectJSON(t, req.Body, "test_result", "tools", 0, "name")
+	expectJSON(t, req.Body, "object", "tools", 0, "input_schema", "type")
+	expectJSON(t, req.Body, "tool", "tool_choice", "type")
+	expectJSON(t, req.Body, "test_result", "tool_choice", "name")
+	if resp.Content != `{"ok":true}` {
+		t.Errorf("Content = %q, want 工具参数", resp.Content)
+	}
+}
+
+func TestAnthropicProviderListModels(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK, `{"data":[{"id":"claude-sonnet"},{"id":"claude-haiku"}],"has_more":false}`)
+	cfg := &LLMConfig{ApiURL: server.URL, ApiKey: "sk-ant"}
+
+	models, err := (&AnthropicProvider{client: server.Client()}).ListModels(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Path != "/v1/models" || req.Header.Get("x-api-key") != "sk-ant" {
+		t.Errorf("请求 %s, x-api-key %q", req.Path, req.Header.Get("x-api-key"))
+	}
+	if want := []string{"claude-sonnet", "claude-haiku"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %v, want %v", models, want)
+	}
+}
+
+func TestOllamaProviderChat(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK,
+		`{"message":{"role":"assistant","content":"你好"},"prompt_eval_count":40,"eval_count":6}`)
+	// 旧配置中的 OpenAI 兼容地址带 /v1 后缀
+	cfg := &LLMConfig{ApiURL: server.URL + "/v1", Model: "qwen2.5"}
+
+	resp, err := (&OllamaProvider{client: server.Client()}).Chat(context.Background(), cfg,
+		ChatRequest{System: "系统", User: "用户", Schema: testSchema})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Method != "POST" || req.Path != "/api/chat" {
+		t.Errorf("请求 %s %s", req.Method, req.Path)
+	}
+	if got := req.Header.Get("Authorization"); got != "" {
+		t.Errorf("Ollama 不应发送 Authorization, got %q", got)
+	}
+	expectJSON(t, req.Body, "qwen2.5", "model")
+	expectJSON(t, req.Body, false, "stream")
+	expectJSON(t, req.Body, "system", "messages", 0, "role")
+	expectJSON(t, req.Body, "用户", "messages", 1, "content")
+	expectJSON(t, req.Body, "object", "format", "type")
+
+	want := &ChatResponse{Content: "你好", Usage: TokenUsage{PromptTokens: 40, CompletionTokens: 6}}
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("Chat() = %+v, want %+v", resp, want)
+	}
+}
+
+func TestOllamaProviderListModels(t *testing.T) {
+	server, req := newProviderServer(t, http.StatusOK, `{"models":[{"name":"qwen2.5:7b"},{"name":"llama3.2"}]}`)
+	cfg := &LLMConfig{ApiURL: server.URL + "/api"}
+
+	models, err := (&OllamaProvider{client: server.Client()}).ListModels(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "GET" || req.Path != "/api/tags" {
+		t.Errorf("请求 %s %s", req.Method, req.Path)
+	}
+	if want := []string{"qwen2.5:7b", "llama3.2"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %v, want %v", models, want)
+	}
+}
+
+func TestProviderAPIError(t *testing.T) {
+	for _, name := range []string{"openai", "google", "anthropic", "ollama"} {
+		t.Run(name, func(t *testing.T) {
+			server, _ := newProviderServer(t, http.StatusTooManyRequests, `{"error":"rate limited"}`)
+			p, err := NewProvider(name, server.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := &LLMConfig{ApiURL: server.URL, ApiKey: "key", Model: "m"}
+
+			_, chatErr := p.Chat(context.Background(), cfg, ChatRequest{User: "Hi"})
+			_, listErr := p.ListModels(context.Background(), cfg)
+			for _, err := range []error{chatErr, listErr} {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("err = %v, want *APIError", err)
+				}
+				if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter != 7*time.Second || !apiErr.Retryable() {
+					t.Errorf("APIError = %+v", apiErr)
+				}
+				if apiErr.Body != `{"error":"rate limited"}` {
+					t.Errorf("Body = %q", apiErr.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestDoJSONInvalidBody(t *testing.T) {
+	server, _ := newProviderServer(t, http.StatusOK, `not json`)
+	req, _ := http.NewRequest("GET", server.URL, nil)
+
+	var out map[string]interface{}
+	err := doJSON(server.Client(), req, &out)
+	var apiErr *APIError
+	if err == nil || errors.As(err, &apiErr) {
+		t.Errorf("doJSON() = %v, want 解析错误", err)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name string
+		want Provider
+	}{
+		{"", &OpenAIProvider{}},
+		{"openai", &OpenAIProvider{}},
+		{"google", &GoogleProvider{}},
+		{"anthropic", &AnthropicProvider{}},
+		{"ollama", &OllamaProvider{}},
+		// 未知的提供商按 OpenAI 兼容接口处理
+		{"deepseek", &OpenAIProvider{}},
+	}
+	for _, tt := range tests {
+		p, err := NewProvider(tt.name, nil)
+		if err != nil {
+			t.Errorf("NewProvider(%q) error: %v", tt.name, err)
+			continue
+		}
+		if reflect.TypeOf(p) != reflect.TypeOf(tt.want) {
+			t.Errorf("NewProvider(%q) = %T, want %T", tt.name, p, tt.want)
+		}
+	}
+}
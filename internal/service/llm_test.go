@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-news/internal/model"
+)
+
+// 不支持 json_schema 的兼容接口返回400时,改为提示词约束重新请求
+func TestChatJSONFallbackWithoutSchema(t *testing.T) {
+	var mu sync.Mutex
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		mu.Lock()
+		requests = append(requests, body)
+		mu.Unlock()
+
+		if _, ok := body["response_format"]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"message":"This response_format type is unavailable now"}}`)
+			return
+		}
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"{\"worth\":true,\"reason\":\"发布新版本\",\"score\":80,\"category\":\"编程\",\"tags\":[\"Go\"]}"}}]}`)
+	}))
+	defer server.Close()
+
+	db := newTestDB(t, &model.Config{}, &model.LLMUsage{}, &model.LLMCall{})
+	for key, value := range map[string]string{
+		model.ConfigLLMProvider:         "openai",
+		model.ConfigLLMApiURL:           server.URL,
+		model.ConfigLLMModel:            "deepseek-chat",
+		model.ConfigLLMStructuredOutput: "true",
+	} {
+		db.Create(&model.Config{Key: key, Value: value})
+	}
+	s := NewLLMService(db)
+
+	var result FilterResult
+	if err := s.ChatJSON(context.Background(), ChatMeta{Stage: model.StageFilter}, "筛选文章", "Go 1.25 released", filterSchema, &result); err != nil {
+		t.Fatalf("ChatJSON() error = %v", err)
+	}
+	if !result.Worth || result.Score != 80 || result.Category != "编程" {
+		t.Errorf("result = %+v", result)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("请求 %d 次, want 2", len(requests))
+	}
+	if _, ok := requests[1]["response_format"]; ok {
+		t.Error("重新请求时不应再发送 response_format")
+	}
+	system := requests[1]["messages"].([]interface{})[0].(map[string]interface{})["content"].(string)
+	if !strings.HasPrefix(system, "筛选文章") || !strings.Contains(system, `"worth"`) {
+		t.Errorf("重新请求的系统提示词没有附上输出格式: %q", system)
+	}
+
+	// 之后的请求直接使用提示词约束
+	if err := s.ChatJSON(context.Background(), ChatMeta{Stage: model.StageFilter}, "筛选文章", "Go 1.26 released", filterSchema, &result); err != nil {
+		t.Fatalf("ChatJSON() error = %v", err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("请求 %d 次, want 3", len(requests))
+	}
+	if _, ok := requests[2]["response_format"]; ok {
+		t.Error("接口拒绝过结构化输出后不应再发送 response_format")
+	}
+}
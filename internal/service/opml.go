@@ -0,0 +1,211 @@
+package service
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+// OPML 2.0 文档结构
+type OPML struct {
+	XMLName xml.Name    `xml:"opml"`
+	Version string      `xml:"version,attr"`
+	Head    OPMLHead    `xml:"head"`
+	Body    []OPMLEntry `xml:"body>outline"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type OPMLEntry struct {
+	Text     string      `xml:"text,attr"`
+	Title    string      `xml:"title,attr,omitempty"`
+	Type     string      `xml:"type,attr,omitempty"`
+	XMLURL   string      `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string      `xml:"htmlUrl,attr,omitempty"`
+	Category string      `xml:"category,attr,omitempty"`
+	Outlines []OPMLEntry `xml:"outline"`
+}
+
+// OPML导入结果状态
+const (
+	OPMLImportCreated   = "created"
+	OPMLImportDuplicate = "duplicate"
+	OPMLImportInvalid   = "invalid"
+	OPMLImportFailed    = "failed"
+)
+
+// OPMLImportResult 单个条目的导入结果
+type OPMLImportResult struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Folder string `json:"folder"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type OPMLService struct {
+	db *gorm.DB
+}
+
+func NewOPMLService(db *gorm.DB) *OPMLService {
+	return &OPMLService{db: db}
+}
+
+// Import 导入OPML订阅列表,分类映射为Feed文件夹
+func (s *OPMLService) Import(r io.Reader) ([]OPMLImportResult, error) {
+	var doc OPML
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("解析OPML失败: %v", err)
+	}
+
+	results := make([]OPMLImportResult, 0)
+	seen := make(map[string]bool)
+	s.importEntries(doc.Body, "", seen, &results)
+	return results, nil
+}
+
+func (s *OPMLService) importEntries(entries []OPMLEntry, folder string, seen map[string]bool, results *[]OPMLImportResult) {
+	for _, entry := range entries {
+		name := entry.Title
+		if name == "" {
+			name = entry.Text
+		}
+
+		// 没有 xmlUrl 的节点视为分类
+		if entry.XMLURL == "" {
+			if len(entry.Outlines) > 0 {
+				s.importEntries(entry.Outlines, joinFolder(folder, name), seen, results)
+			}
+			continue
+		}
+
+		result := OPMLImportResult{
+			Name:   name,
+			URL:    strings.TrimSpace(entry.XMLURL),
+			Folder: folder,
+		}
+		if result.Folder == "" {
+			result.Folder = categoryFolder(entry.Category)
+		}
+		if result.Name == "" {
+			result.Name = result.URL
+		}
+
+		switch {
+		case !strings.HasPrefix(result.URL, "http://") && !strings.HasPrefix(result.URL, "https://"):
+			result.Status = OPMLImportInvalid
+			result.Error = "无效的URL"
+		case seen[result.URL]:
+			result.Status = OPMLImportDuplicate
+			result.Error = "文件中重复"
+		default:
+			seen[result.URL] = true
+			s.createFeed(&result)
+		}
+
+		*results = append(*results, result)
+	}
+}
+
+func (s *OPMLService) createFeed(result *OPMLImportResult) {
+	var count int64
+	s.db.Model(&model.Feed{}).Where("url = ?", result.URL).Count(&count)
+	if count > 0 {
+		result.Status = OPMLImportDuplicate
+		result.Error = "订阅源已存在"
+		return
+	}
+
+	feed := model.Feed{
+		Name:    result.Name,
+		URL:     result.URL,
+		Folder:  result.Folder,
+		Enabled: true,
+	}
+	if err := s.db.Create(&feed).Error; err != nil {
+		result.Status = OPMLImportFailed
+		result.Error = err.Error()
+		return
+	}
+	result.Status = OPMLImportCreated
+}
+
+// Export 导出所有订阅源为OPML 2.0
+func (s *OPMLService) Export() ([]byte, error) {
+	var feeds []model.Feed
+	if err := s.db.Order("folder, name").Find(&feeds).Error; err != nil {
+		return nil, err
+	}
+
+	doc := OPML{
+		Version: "2.0",
+		Head: OPMLHead{
+			Title:       "go-news subscriptions",
+			DateCreated: time.Now().Format(time.RFC1123Z),
+		},
+	}
+
+	folders := make(map[string]*OPMLEntry)
+	var folderNames []string
+	for _, feed := range feeds {
+		entry := OPMLEntry{
+			Text:   feed.Name,
+			Title:  feed.Name,
+			Type:   "rss",
+			XMLURL: feed.URL,
+		}
+
+		if feed.Folder == "" {
+			doc.Body = append(doc.Body, entry)
+			continue
+		}
+
+		folder, ok := folders[feed.Folder]
+		if !ok {
+			folder = &OPMLEntry{Text: feed.Folder, Title: feed.Folder}
+			folders[feed.Folder] = folder
+			folderNames = append(folderNames, feed.Folder)
+		}
+		folder.Outlines = append(folder.Outlines, entry)
+	}
+
+	sort.Strings(folderNames)
+	for _, name := range folderNames {
+		doc.Body = append(doc.Body, *folders[name])
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func joinFolder(parent, name string) string {
+	name = strings.TrimSpace(name)
+	if parent == "" {
+		return name
+	}
+	if name == "" {
+		return parent
+	}
+	return parent + "/" + name
+}
+
+// categoryFolder 将 OPML category 属性 (如 "/Tech/News,/Other") 转为文件夹名
+func categoryFolder(category string) string {
+	if category == "" {
+		return ""
+	}
+	first := strings.Split(category, ",")[0]
+	return strings.Trim(strings.TrimSpace(first), "/")
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	outputDefaultLimit = 50
+	outputMaxLimit     = 200
+)
+
+// OutputFilter 输出Feed的筛选条件
+type OutputFilter struct {
+	FeedID uint
+	Folder string
+	Tag    string
+	Limit  int
+}
+
+// OutputMeta 输出Feed的元信息
+type OutputMeta struct {
+	Title   string
+	HomeURL string // 站点首页
+	SelfURL string // 当前Feed地址
+}
+
+type OutputService struct {
+	db *gorm.DB
+}
+
+func NewOutputService(db *gorm.DB) *OutputService {
+	return &OutputService{db: db}
+}
+
+// Articles 查询已处理的文章,按发布时间倒序
+func (s *OutputService) Articles(filter OutputFilter) ([]model.Article, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = outputDefaultLimit
+	}
+	if limit > outputMaxLimit {
+		limit = outputMaxLimit
+	}
+
+	query := s.db.Model(&model.Article{}).Preload("Feed").Preload("Tags").
+		Where("articles.status = ?", model.StatusProcessed)
+
+	if filter.FeedID > 0 {
+		query = query.Where("articles.feed_id = ?", filter.FeedID)
+	}
+	if filter.Folder != "" {
+		query = query.Joins("JOIN feeds ON feeds.id = articles.feed_id").
+			Where("feeds.folder = ?", filter.Folder)
+	}
+	if filter.Tag != "" {
+		query = WhereTag(query, filter.Tag)
+	}
+
+	var articles []model.Article
+	err := query.Order("articles.pub_date DESC").Limit(limit).Find(&articles).Error
+	return articles, err
+}
+
+// ===== RSS 2.0 =====
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      rssSelf   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link"`
+	Description string     `xml:"description"`
+	GUID        rssGUID    `xml:"guid"`
+	PubDate     string     `xml:"pubDate"`
+	Source      *rssSource `xml:"source,omitempty"`
+	Categories  []string   `xml:"category"`
+}
+
+// rssSource 文章来源的订阅源,RSS 2.0 要求 url 属性
+type rssSource struct {
+	URL  string `xml:"url,attr"`
+	Name string `xml:",chardata"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// RenderRSS 生成 RSS 2.0
+func (s *OutputService) RenderRSS(meta OutputMeta, articles []model.Article) ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         meta.Title,
+			Link:          meta.HomeURL,
+			Description:   meta.Title,
+			AtomLink:      rssSelf{Href: meta.SelfURL, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: time.Now().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, a := range articles {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       a.Title,
+			Link:        a.Link,
+			Description: a.Summary,
+			GUID:        rssGUID{Value: a.Link, IsPermaLink: true},
+			PubDate:     a.PubDate.Format(time.RFC1123Z),
+			Source:      articleSource(a),
+			Categories:  articleCategories(a),
+		})
+	}
+
+	return marshalXML(doc)
+}
+
+// articleSource 订阅源地址为空时不输出 source
+func articleSource(a model.Article) *rssSource {
+	if a.Feed.URL == "" {
+		return nil
+	}
+	return &rssSource{URL: a.Feed.URL, Name: a.Feed.Name}
+}
+
+// ===== Atom =====
+
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    atomText       `xml:"summary"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// RenderAtom 生成 Atom 1.0
+func (s *OutputService) RenderAtom(meta OutputMeta, articles []model.Article) ([]byte, error) {
+	doc := atomDocument{
+		Title:   meta.Title,
+		ID:      meta.SelfURL,
+		Updated: time.Now().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: meta.SelfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: meta.HomeURL, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for _, a := range articles {
+		updated := a.PubDate
+		if a.ProcessedAt != nil {
+			updated = *a.ProcessedAt
+		}
+		entry := atomEntry{
+			Title:     a.Title,
+			ID:        a.Link,
+			Link:      atomLink{Href: a.Link, Rel: "alternate"},
+			Published: a.PubDate.Format(time.RFC3339),
+			Updated:   updated.Format(time.RFC3339),
+			Summary:   atomText{Type: "text", Value: a.Summary},
+		}
+		if a.Feed.Name != "" {
+			entry.Author = &atomAuthor{Name: a.Feed.Name}
+		}
+		for _, term := range articleCategories(a) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: term})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return marshalXML(doc)
+}
+
+// ===== JSON Feed 1.1 =====
+
+type jsonFeedDocument struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentText   string           `json:"content_text"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// RenderJSONFeed 生成 JSON Feed 1.1
+func (s *OutputService) RenderJSONFeed(meta OutputMeta, articles []model.Article) ([]byte, error) {
+	doc := jsonFeedDocument{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       meta.Title,
+		HomePageURL: meta.HomeURL,
+		FeedURL:     meta.SelfURL,
+		Items:       make([]jsonFeedItem, 0, len(articles)),
+	}
+
+	for _, a := range articles {
+		item := jsonFeedItem{
+			ID:            fmt.Sprintf("%d", a.ID),
+			URL:           a.Link,
+			Title:         a.Title,
+			ContentText:   a.Summary,
+			DatePublished: a.PubDate.Format(time.RFC3339),
+			Tags:          articleCategories(a),
+		}
+		if a.ProcessedAt != nil {
+			item.DateModified = a.ProcessedAt.Format(time.RFC3339)
+		}
+		if a.Feed.Name != "" {
+			item.Authors = []jsonFeedAuthor{{Name: a.Feed.Name}}
+		}
+		doc.Items = append(doc.Items, item)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// articleCategories 文章分类和标签,用作输出Feed的 category/tags
+func articleCategories(a model.Article) []string {
+	var categories []string
+	if a.Category != "" {
+		categories = append(categories, a.Category)
+	}
+	for _, tag := range a.Tags {
+		categories = append(categories, tag.Name)
+	}
+	return categories
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
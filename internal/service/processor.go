@@ -2,7 +2,7 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
 	"strings"
 	"sync"
@@ -12,50 +12,136 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	// 失败后的重试间隔: processRetryBase * 2^(失败次数-1),最长 processRetryMax
+	processRetryBase = 5 * time.Minute
+	processRetryMax  = 6 * time.Hour
+	// 超过该次数标记为处理失败,不再自动重试
+	processMaxAttempts = 5
+)
+
 type ProcessorService struct {
-	db  *gorm.DB
-	llm *LLMService
+	db       *gorm.DB
+	llm      *LLMService
+	usage    *UsageService
+	calls    *CallLogService
+	tags     *TagService
+	prompts  *PromptService
+	feedback *FeedbackService
+	entities *EntityService
+	cluster  *ClusterService
 }
 
 func NewProcessorService(db *gorm.DB, llm *LLMService) *ProcessorService {
-	return &ProcessorService{db: db, llm: llm}
+	return &ProcessorService{
+		db:       db,
+		llm:      llm,
+		usage:    NewUsageService(db),
+		calls:    NewCallLogService(db),
+		tags:     NewTagService(db),
+		prompts:  NewPromptService(db),
+		feedback: NewFeedbackService(db),
+		entities: NewEntityService(db),
+		cluster:  NewClusterService(db),
+	}
 }
 
 // FilterResult 筛选结果
 type FilterResult struct {
-	Worth  bool   `json:"worth"`
-	Reason string `json:"reason"`
+	Worth    bool     `json:"worth"`
+	Reason   string   `json:"reason"`
+	Score    int      `json:"score"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Summary  string   `json:"summary,omitempty"` // 仅合并模式
 }
 
 // ProcessArticle 处理单篇文章
 func (s *ProcessorService) ProcessArticle(ctx context.Context, article *model.Article) error {
-	// 1. 筛选
-	filterPrompt := s.llm.GetPrompt(model.ConfigPromptFilter)
-	filterResp, err := s.llm.Chat(ctx, filterPrompt, article.Title+"\n\n"+article.Content)
+	cfg, err := s.llm.GetConfig()
+	if err != nil {
+		return err
+	}
+	// 升级前抓取的文章没有语言,处理时补充检测
+	if article.Language == "" {
+		var feed model.Feed
+		s.db.Select("language").Limit(1).Find(&feed, article.FeedID)
+		article.Language = DetectLanguage(article.Title+"\n"+article.BodyText(), feed.Language)
+	}
+	prompts, err := s.prompts.ForArticle(article)
 	if err != nil {
 		return err
 	}
+	// Feed 或文件夹覆盖了提示词时按两步处理,保证覆盖的提示词生效
+	combined := configValue(s.db, model.ConfigProcessMode) == model.ProcessModeCombined && !prompts.Overridden
 
-	var result FilterResult
-	if err := json.Unmarshal([]byte(filterResp), &result); err != nil {
-		// 简单处理:包含"不"或"no"认为不重要
-		result.Worth = !strings.Contains(strings.ToLower(filterResp), "不值得") &&
-			!strings.Contains(strings.ToLower(filterResp), "no")
+	// 1. 筛选,合并模式下同时生成摘要
+	prompt, schema, stage, version := prompts.Filter, filterSchema, model.StageFilter, prompts.FilterVersion
+	if combined {
+		prompt, schema, stage, version = prompts.Combined, combinedSchema, model.StageCombined, prompts.CombinedVersion
+	}
+	// 附加用户最近的反馈作为示例,不计入提示词版本
+	prompt += s.feedback.FewShot(parseIntConfig(configValue(s.db, model.ConfigFewShotCount), 0), article.ID)
+
+	result, truncated, err := s.filter(ctx, cfg, ChatMeta{ArticleID: article.ID, Stage: stage}, article, prompt, schema)
+	if err != nil {
+		return err
 	}
 
 	now := time.Now()
+	article.Score = result.Score
+	article.Category = strings.TrimSpace(result.Category)
+	article.FilterPromptID = optionalID(version)
+	article.SummaryPromptID = nil
+	article.ProcessedModel = cfg.Model
+	article.TranslatedTitle = ""
 
-	if !result.Worth {
-		// 标记为已过滤
+	tags, err := s.tags.Resolve(result.Tags)
+	if err != nil {
+		return err
+	}
+
+	// 2. 翻译标题,已过滤的文章也翻译,失败不影响处理结果
+	if s.needsTranslation(article) {
+		translated, err := s.llm.Chat(ctx, ChatMeta{ArticleID: article.ID, Stage: model.StageTranslate}, prompts.Translate, article.Title)
+		if err != nil {
+			log.Printf("[Processor] 标题翻译失败 [%s]: %v", article.Title, err)
+		} else {
+			article.TranslatedTitle = cleanTranslation(translated)
+		}
+	}
+
+	if !s.worthReading(result) {
+		// 标记为已过滤,只按规则提取实体
+		entities, err := s.extractEntities(ctx, cfg, article, prompts.Entities, false)
+		if err != nil {
+			return err
+		}
 		article.Status = model.StatusFiltered
 		article.Summary = result.Reason
 		article.ProcessedAt = &now
-		return s.db.Save(article).Error
+		if err := s.save(article, tags, entities); err != nil {
+			return err
+		}
+		// 其他订阅源的同一事件报道可能按各自的提示词值得阅读
+		s.promote(article)
+		return nil
 	}
 
-	// 2. 生成摘要
-	summaryPrompt := s.llm.GetPrompt(model.ConfigPromptSummary)
-	summary, err := s.llm.Chat(ctx, summaryPrompt, article.Title+"\n\n"+article.Content)
+	// 3. 生成摘要,合并模式下正文被截断时改为分段摘要
+	summary := strings.TrimSpace(result.Summary)
+	if !combined || truncated || summary == "" {
+		summary, err = s.summarize(ctx, cfg.Limit, ChatMeta{ArticleID: article.ID, Stage: model.StageSummary},
+			article, prompts.Summary, prompts.ChunkSummary)
+		if err != nil {
+			return err
+		}
+		version = prompts.SummaryVersion
+	}
+	article.SummaryPromptID = optionalID(version)
+
+	// 4. 提取实体
+	entities, err := s.extractEntities(ctx, cfg, article, prompts.Entities, true)
 	if err != nil {
 		return err
 	}
@@ -64,20 +150,226 @@ func (s *ProcessorService) ProcessArticle(ctx context.Context, article *model.Ar
 	article.Summary = summary
 	article.ProcessedAt = &now
 
-	return s.db.Save(article).Error
+	return s.save(article, tags, entities)
+}
+
+// worthReading 筛选结论为值得阅读且相关度不低于阈值
+func (s *ProcessorService) worthReading(result *FilterResult) bool {
+	threshold := parseIntConfig(configValue(s.db, model.ConfigScoreThreshold), 0)
+	return result.Worth && result.Score >= threshold
+}
+
+// needsTranslation 开启了标题翻译且文章语言与目标语言不同
+func (s *ProcessorService) needsTranslation(article *model.Article) bool {
+	if configValue(s.db, model.ConfigTranslateTitle) != "true" || article.Language == "" {
+		return false
+	}
+	return NormalizeLanguage(article.Language) != TargetLanguage(s.db)
+}
+
+// cleanTranslation 只保留译文的第一行并去掉模型常加的引号
+func cleanTranslation(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(strings.Trim(text, "\"'“”「」《》"))
+}
+
+// extractEntities 按配置提取实体: 规则结果总是保留,llm 模式下值得阅读的文章再由LLM提取,
+// LLM 提取失败时只使用规则结果
+func (s *ProcessorService) extractEntities(ctx context.Context, cfg *LLMConfig, article *model.Article,
+	prompt string, worth bool) ([]model.Entity, error) {
+	mode := configValue(s.db, model.ConfigEntityExtraction)
+	if mode == model.EntityExtractionOff {
+		return nil, nil
+	}
+
+	text := article.Title + "\n\n" + article.BodyText()
+	items := ExtractRules(text)
+	if mode == model.EntityExtractionLLM && worth {
+		input, _ := cfg.Limit.truncateTokens(text, cfg.Limit.InputBudget(prompt), model.TruncateHead)
+		var result struct {
+			Entities []ExtractedEntity `json:"entities"`
+		}
+		meta := ChatMeta{ArticleID: article.ID, Stage: model.StageEntities}
+		if err := s.llm.ChatJSON(ctx, meta, prompt, input, entitySchema, &result); err != nil {
+			log.Printf("[Processor] 实体提取失败,只使用规则结果 [%s]: %v", article.Title, err)
+		} else {
+			items = append(result.Entities, items...)
+		}
+	}
+	return s.entities.Resolve(items)
+}
+
+// filter 调用筛选提示词,正文超出模型上下文时按配置截断,返回结果和是否发生了截断
+func (s *ProcessorService) filter(ctx context.Context, cfg *LLMConfig, meta ChatMeta, article *model.Article,
+	prompt string, schema *JSONSchema) (*FilterResult, bool, error) {
+	input, truncated := cfg.Limit.truncateTokens(article.Title+"\n\n"+article.BodyText(),
+		cfg.Limit.InputBudget(prompt), configValue(s.db, model.ConfigFilterTruncation))
+	if truncated {
+		log.Printf("[Processor] 文章过长,筛选时已截断 [%s]", article.Title)
+	}
+
+	var result FilterResult
+	if err := s.llm.ChatJSON(ctx, meta, prompt, input, schema, &result); err != nil {
+		return nil, truncated, err
+	}
+	return &result, truncated, nil
+}
+
+// summarize 生成摘要。正文超出模型上下文时先用 chunkPrompt 分段摘要,
+// 再把各段要点合并生成最终摘要 (map-reduce)
+func (s *ProcessorService) summarize(ctx context.Context, limit ModelLimit, meta ChatMeta, article *model.Article,
+	prompt, chunkPrompt string) (string, error) {
+	content := article.Title + "\n\n" + article.BodyText()
+
+	budget := limit.InputBudget(prompt)
+	if budget == 0 || limit.EstimateTokens(content) <= budget {
+		return s.llm.Chat(ctx, meta, prompt, content)
+	}
+
+	chunkMeta := meta
+	if meta.Stage == model.StageSummary {
+		chunkMeta.Stage = model.StageSummaryChunk
+	}
+	chunkBudget := limit.InputBudget(chunkPrompt) - limit.EstimateTokens(article.Title) - 16
+	if chunkBudget < minInputBudget {
+		chunkBudget = minInputBudget
+	}
+
+	// 分段摘要,合并后仍然过长时对要点再分段,最多 maxReduceRounds 轮
+	text := article.BodyText()
+	for round := 0; round < maxReduceRounds && limit.EstimateTokens(content) > budget; round++ {
+		chunks := limit.splitChunks(text, chunkBudget)
+		log.Printf("[Processor] 文章过长,分 %d 段生成摘要 [%s]", len(chunks), article.Title)
+
+		points := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			point, err := s.llm.Chat(ctx, chunkMeta, chunkPrompt,
+				fmt.Sprintf("%s (第 %d/%d 部分)\n\n%s", article.Title, i+1, len(chunks), chunk))
+			if err != nil {
+				return "", err
+			}
+			points = append(points, strings.TrimSpace(point))
+		}
+
+		text = strings.Join(points, "\n\n")
+		content = article.Title + "\n\n以下是文章各部分的要点:\n\n" + text
+	}
+
+	content, _ = limit.truncateTokens(content, budget, model.TruncateHead)
+	return s.llm.Chat(ctx, meta, prompt, content)
+}
+
+// optionalID 0 表示未设置
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// save 保存处理结果并替换文章标签和实体,同时清除失败重试记录
+func (s *ProcessorService) save(article *model.Article, tags []model.Tag, entities []model.Entity) error {
+	article.Attempts = 0
+	article.LastError = ""
+	article.NextRetryAt = nil
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tags", "Entities").Save(article).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(article).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+		return tx.Model(article).Association("Entities").Replace(entities)
+	})
+}
+
+// recordFailure 记录处理失败,按指数退避安排重试,超过最大次数后标记为失败
+func (s *ProcessorService) recordFailure(article *model.Article, processErr error) error {
+	article.Attempts++
+	article.LastError = processErr.Error()
+
+	if article.Attempts >= processMaxAttempts {
+		article.Status = model.StatusFailed
+		article.NextRetryAt = nil
+	} else {
+		delay := processRetryBase << uint(article.Attempts-1)
+		if delay > processRetryMax {
+			delay = processRetryMax
+		}
+		next := time.Now().Add(delay)
+		article.NextRetryAt = &next
+	}
+
+	if err := s.db.Model(article).Updates(map[string]interface{}{
+		"status":        article.Status,
+		"attempts":      article.Attempts,
+		"last_error":    article.LastError,
+		"next_retry_at": article.NextRetryAt,
+	}).Error; err != nil {
+		return err
+	}
+	if article.Status == model.StatusFailed {
+		s.promote(article)
+	}
+	return nil
+}
+
+// promote 代表文章没有生成摘要时,由同一事件的下一篇重复报道代替处理
+func (s *ProcessorService) promote(article *model.Article) {
+	if err := s.cluster.Promote(article); err != nil {
+		log.Printf("[Processor] 更换事件代表文章失败 [%s]: %v", article.Title, err)
+	}
+}
+
+// RetryArticles 将失败的文章重新放回待处理队列,ids为空时重试全部失败文章
+func (s *ProcessorService) RetryArticles(ids []uint) (int64, error) {
+	query := s.db.Model(&model.Article{}).Where("status = ?", model.StatusFailed)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"status":        model.StatusPending,
+		"attempts":      0,
+		"last_error":    "",
+		"next_retry_at": nil,
+	})
+	return result.RowsAffected, result.Error
+}
+
+// pendingQuery 待处理且已到重试时间的文章
+func (s *ProcessorService) pendingQuery() *gorm.DB {
+	return s.db.Model(&model.Article{}).
+		Where("status = ?", model.StatusPending).
+		Where("next_retry_at IS NULL OR next_retry_at <= ?", time.Now())
 }
 
 // ProcessPendingArticles 批量处理未处理的文章,直到全部处理完成
 func (s *ProcessorService) ProcessPendingArticles(ctx context.Context, limit int) error {
+	// 清理过期的调用记录
+	if removed, err := s.calls.Prune(); err != nil {
+		log.Printf("[Processor] 清理LLM调用记录失败: %v", err)
+	} else if removed > 0 {
+		log.Printf("[Processor] 已清理 %d 条过期的LLM调用记录", removed)
+	}
+
 	// 获取待处理文章总数
 	var total int64
-	s.db.Model(&model.Article{}).Where("status = ?", model.StatusPending).Count(&total)
+	s.pendingQuery().Count(&total)
 
 	if total == 0 {
 		log.Println("[Processor] 没有待处理的文章")
 		return nil
 	}
 
+	if err := s.usage.CheckBudget(); err != nil {
+		log.Printf("[Processor] %v", err)
+		return err
+	}
+
 	log.Printf("[Processor] 开始处理,共 %d 篇待处理文章", total)
 
 	// 使用并发处理,最多同时处理的文章数
@@ -91,7 +383,7 @@ func (s *ProcessorService) ProcessPendingArticles(ctx context.Context, limit int
 	// 分批循环处理,直到没有待处理文章
 	for {
 		var articles []model.Article
-		s.db.Where("status = ?", model.StatusPending).
+		s.pendingQuery().
 			Order("pub_date DESC").
 			Limit(limit).
 			Find(&articles)
@@ -107,6 +399,13 @@ func (s *ProcessorService) ProcessPendingArticles(ctx context.Context, limit int
 				log.Printf("[Processor] 处理中断: 已处理 %d 篇, 失败 %d 篇", processed, failed)
 				return ctx.Err()
 			default:
+				// 预算用尽时等待进行中的任务完成后停止
+				if err := s.usage.CheckBudget(); err != nil {
+					wg.Wait()
+					log.Printf("[Processor] %v: 已处理 %d 篇, 失败 %d 篇", err, processed, failed)
+					return err
+				}
+
 				wg.Add(1)
 				semaphore <- struct{}{} // 获取信号量
 
@@ -116,6 +415,12 @@ func (s *ProcessorService) ProcessPendingArticles(ctx context.Context, limit int
 
 					if err := s.ProcessArticle(ctx, &art); err != nil {
 						log.Printf("[Processor] 处理文章失败 [%s]: %v", art.Title, err)
+						// 任务被取消不计入失败次数
+						if ctx.Err() == nil {
+							if saveErr := s.recordFailure(&art, err); saveErr != nil {
+								log.Printf("[Processor] 保存失败状态出错 [%s]: %v", art.Title, saveErr)
+							}
+						}
 						mu.Lock()
 						failed++
 						mu.Unlock()
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"go-news/internal/model"
+)
+
+// 重试后处理成功时清除失败记录
+func TestSaveClearsRetryState(t *testing.T) {
+	db := newTestDB(t, &model.Article{}, &model.Story{}, &model.Tag{}, &model.Entity{}, &model.Config{})
+	p := NewProcessorService(db, nil)
+
+	article := model.Article{Title: "Go 1.25 released", Link: "https://example.com/go-1.25"}
+	db.Create(&article)
+	if err := p.recordFailure(&article, errors.New("HTTP 500")); err != nil {
+		t.Fatal(err)
+	}
+
+	var failed model.Article
+	db.First(&failed, article.ID)
+	if failed.Attempts != 1 || failed.NextRetryAt == nil {
+		t.Fatalf("失败后 attempts=%d next_retry_at=%v", failed.Attempts, failed.NextRetryAt)
+	}
+
+	failed.Status = model.StatusProcessed
+	failed.Summary = "摘要"
+	if err := p.save(&failed, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	var got model.Article
+	db.First(&got, article.ID)
+	if got.Status != model.StatusProcessed || got.Attempts != 0 || got.LastError != "" || got.NextRetryAt != nil {
+		t.Errorf("处理成功后 status=%d attempts=%d last_error=%q next_retry_at=%v",
+			got.Status, got.Attempts, got.LastError, got.NextRetryAt)
+	}
+}
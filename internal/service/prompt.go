@@ -0,0 +1,300 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+// PromptData 提示词模板可用的变量
+type PromptData struct {
+	FeedName string
+	Folder   string
+	Title    string
+	Link     string
+	PubDate  string
+	Language string // 文章语言,未检测到时使用订阅源声明的语言
+
+	TargetLanguage string // 目标语言名称,如"中文"
+}
+
+// PromptService 按 Feed、文件夹、全局的顺序查找提示词并渲染模板
+type PromptService struct {
+	db *gorm.DB
+}
+
+func NewPromptService(db *gorm.DB) *PromptService {
+	return &PromptService{db: db}
+}
+
+// Prompts 一篇文章处理时使用的提示词
+type Prompts struct {
+	Filter       string
+	Summary      string
+	Combined     string
+	ChunkSummary string
+	Translate    string
+	Entities     string
+	Overridden   bool // Feed 或文件夹覆盖了筛选或摘要提示词
+
+	// 所用提示词的版本ID
+	FilterVersion   uint
+	SummaryVersion  uint
+	CombinedVersion uint
+}
+
+// ForArticle 解析并渲染文章使用的提示词,同时记录所用的版本
+func (s *PromptService) ForArticle(article *model.Article) (*Prompts, error) {
+	var feed model.Feed
+	if article.FeedID > 0 {
+		s.db.Limit(1).Find(&feed, article.FeedID)
+	}
+
+	var folder model.FolderPrompt
+	if feed.Folder != "" {
+		s.db.Where("folder = ?", feed.Folder).Limit(1).Find(&folder)
+	}
+
+	filter := s.choose(model.ConfigPromptFilter, &feed, feed.PromptFilter, folder.PromptFilter)
+	summary := s.choose(model.ConfigPromptSummary, &feed, feed.PromptSummary, folder.PromptSummary)
+	combined := s.choose(model.ConfigPromptCombined, &feed, "", "")
+
+	prompts := &Prompts{
+		Filter:       filter.Content,
+		Summary:      summary.Content,
+		Combined:     combined.Content,
+		ChunkSummary: configValue(s.db, model.ConfigPromptChunkSummary),
+		Translate:    configValue(s.db, model.ConfigPromptTranslate),
+		Entities:     configValue(s.db, model.ConfigPromptEntities),
+		Overridden:   filter.Scope != model.PromptScopeGlobal || summary.Scope != model.PromptScopeGlobal,
+	}
+
+	for _, item := range []struct {
+		source promptSource
+		id     *uint
+	}{
+		{filter, &prompts.FilterVersion},
+		{summary, &prompts.SummaryVersion},
+		{combined, &prompts.CombinedVersion},
+	} {
+		version, err := s.Record(item.source.Key, item.source.Scope, item.source.Content)
+		if err != nil {
+			return nil, err
+		}
+		if version != nil {
+			*item.id = version.ID
+		}
+	}
+
+	data := s.promptData(article, &feed)
+	for _, p := range []*string{&prompts.Filter, &prompts.Summary, &prompts.Combined, &prompts.ChunkSummary, &prompts.Translate, &prompts.Entities} {
+		rendered, err := RenderPrompt(*p, data)
+		if err != nil {
+			return nil, err
+		}
+		*p = rendered
+	}
+	return prompts, nil
+}
+
+// promptSource 选中的提示词模板及其来源
+type promptSource struct {
+	Key     string
+	Scope   string
+	Content string
+}
+
+// choose 按订阅源、文件夹、全局的顺序选择提示词
+func (s *PromptService) choose(key string, feed *model.Feed, feedValue, folderValue string) promptSource {
+	if strings.TrimSpace(feedValue) != "" {
+		return promptSource{Key: key, Scope: model.FeedPromptScope(feed.ID), Content: feedValue}
+	}
+	if strings.TrimSpace(folderValue) != "" {
+		return promptSource{Key: key, Scope: model.FolderPromptScope(feed.Folder), Content: folderValue}
+	}
+	return promptSource{Key: key, Scope: model.PromptScopeGlobal, Content: configValue(s.db, key)}
+}
+
+// promptData 文章对应的模板变量,feed 为空时按 FeedID 查询
+func (s *PromptService) promptData(article *model.Article, feed *model.Feed) PromptData {
+	if feed == nil {
+		feed = &model.Feed{}
+		if article.FeedID > 0 {
+			s.db.Limit(1).Find(feed, article.FeedID)
+		}
+	}
+
+	return PromptData{
+		FeedName: feed.Name,
+		Folder:   feed.Folder,
+		Title:    article.Title,
+		Link:     article.Link,
+		PubDate:  article.PubDate.Format("2006-01-02 15:04"),
+		Language: firstNonEmpty(article.Language, feed.Language),
+
+		TargetLanguage: LanguageName(TargetLanguage(s.db)),
+	}
+}
+
+// Render 用文章的模板变量渲染指定版本的提示词
+func (s *PromptService) Render(version *model.PromptVersion, article *model.Article) (string, error) {
+	return RenderPrompt(version.Content, s.promptData(article, nil))
+}
+
+// promptVersionMu 避免并发处理时为同一内容重复创建版本
+var promptVersionMu sync.Mutex
+
+// Record 记录提示词的当前内容,与最新版本相同时直接返回最新版本,内容为空时不记录
+func (s *PromptService) Record(key, scope, content string) (*model.PromptVersion, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+
+	promptVersionMu.Lock()
+	defer promptVersionMu.Unlock()
+
+	var latest model.PromptVersion
+	s.db.Where("key = ? AND scope = ?", key, scope).Order("version DESC").Limit(1).Find(&latest)
+	if latest.ID > 0 && latest.Content == content {
+		return &latest, nil
+	}
+
+	version := model.PromptVersion{Key: key, Scope: scope, Version: latest.Version + 1, Content: content}
+	if err := s.db.Create(&version).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// Versions 提示词历史版本,key 和 scope 为空时不过滤
+func (s *PromptService) Versions(key, scope string) ([]model.PromptVersion, error) {
+	query := s.db.Model(&model.PromptVersion{})
+	if key != "" {
+		query = query.Where("key = ?", key)
+	}
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+
+	var versions []model.PromptVersion
+	err := query.Order("key, scope, version DESC").Limit(500).Find(&versions).Error
+	return versions, err
+}
+
+// GetVersion 获取单个版本
+func (s *PromptService) GetVersion(id uint) (*model.PromptVersion, error) {
+	var version model.PromptVersion
+	if err := s.db.First(&version, id).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// Restore 将提示词恢复为指定版本的内容,恢复后生成一个新版本
+func (s *PromptService) Restore(id uint) (*model.PromptVersion, error) {
+	version, err := s.GetVersion(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case version.Scope == model.PromptScopeGlobal:
+		err = s.db.Where("key = ?", version.Key).
+			Assign(model.Config{Value: version.Content}).
+			FirstOrCreate(&model.Config{Key: version.Key}).Error
+	case strings.HasPrefix(version.Scope, "feed:"):
+		feedID, _ := strconv.Atoi(strings.TrimPrefix(version.Scope, "feed:"))
+		err = s.db.Model(&model.Feed{}).Where("id = ?", feedID).Update(version.Key, version.Content).Error
+	case strings.HasPrefix(version.Scope, "folder:"):
+		folder := strings.TrimPrefix(version.Scope, "folder:")
+		err = s.db.Where("folder = ?", folder).
+			Assign(map[string]interface{}{version.Key: version.Content}).
+			FirstOrCreate(&model.FolderPrompt{Folder: folder}).Error
+	default:
+		return nil, fmt.Errorf("未知的提示词范围: %s", version.Scope)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Record(version.Key, version.Scope, version.Content)
+}
+
+// ListFolders 全部文件夹的提示词覆盖,按文件夹名索引
+func (s *PromptService) ListFolders() (map[string]model.FolderPrompt, error) {
+	var items []model.FolderPrompt
+	if err := s.db.Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	folders := make(map[string]model.FolderPrompt, len(items))
+	for _, item := range items {
+		folders[item.Folder] = item
+	}
+	return folders, nil
+}
+
+// SaveFolder 保存文件夹的提示词覆盖,两个提示词都为空时删除
+func (s *PromptService) SaveFolder(folder, filter, summary string) error {
+	for _, text := range []string{filter, summary} {
+		if err := ValidatePrompt(text); err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(filter) == "" && strings.TrimSpace(summary) == "" {
+		return s.db.Where("folder = ?", folder).Delete(&model.FolderPrompt{}).Error
+	}
+	err := s.db.Where("folder = ?", folder).
+		Assign(map[string]interface{}{"prompt_filter": filter, "prompt_summary": summary}).
+		FirstOrCreate(&model.FolderPrompt{Folder: folder}).Error
+	if err != nil {
+		return err
+	}
+
+	scope := model.FolderPromptScope(folder)
+	if _, err := s.Record(model.ConfigPromptFilter, scope, filter); err != nil {
+		return err
+	}
+	_, err = s.Record(model.ConfigPromptSummary, scope, summary)
+	return err
+}
+
+// RenderPrompt 按 text/template 渲染提示词,不含模板语法的提示词原样返回
+func RenderPrompt(text string, data PromptData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("prompt").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("提示词模板错误: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("提示词模板错误: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// ValidatePrompt 保存前检查提示词模板能否解析和渲染
+func ValidatePrompt(text string) error {
+	_, err := RenderPrompt(text, PromptData{})
+	return err
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
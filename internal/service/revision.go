@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+// 参与差异比较的最大词数,避免超长文章占用过多内存
+const diffMaxTokens = 3000
+
+// applyUpdate 对比Feed中已知条目的标题和内容,有变化时保存历史版本并更新文章
+func (s *FeedService) applyUpdate(ctx context.Context, feed *model.Feed, existing, incoming *model.Article) error {
+	if strings.TrimSpace(existing.Title) == strings.TrimSpace(incoming.Title) &&
+		strings.TrimSpace(existing.Content) == strings.TrimSpace(incoming.Content) {
+		return nil
+	}
+
+	if feed.FetchFullText {
+		s.fetchFullText(ctx, incoming)
+	}
+	if incoming.FullText == "" {
+		incoming.FullText = existing.FullText
+		incoming.FullHTML = existing.FullHTML
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"title":              incoming.Title,
+		"content":            incoming.Content,
+		"full_html":          incoming.FullHTML,
+		"full_text":          incoming.FullText,
+		"language":           DetectLanguage(incoming.Title+"\n"+incoming.BodyText(), feed.Language),
+		"revision_count":     gorm.Expr("revision_count + 1"),
+		"content_updated_at": &now,
+	}
+
+	// 已处理的文章按配置重新排队
+	requeue := configValue(s.db, model.ConfigReprocessOnUpdate) == "true" &&
+		(existing.Status == model.StatusProcessed || existing.Status == model.StatusFiltered)
+	if requeue {
+		// 与 RetryArticles 一致,清除上次处理留下的失败记录
+		updates["status"] = model.StatusPending
+		updates["attempts"] = 0
+		updates["last_error"] = ""
+		updates["next_retry_at"] = nil
+	}
+	// 标题变化后旧的译文不再适用,重新处理时再翻译
+	if strings.TrimSpace(existing.Title) != strings.TrimSpace(incoming.Title) {
+		updates["translated_title"] = ""
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		revision := model.ArticleRevision{
+			ArticleID: existing.ID,
+			Title:     existing.Title,
+			Content:   existing.Content,
+			FullText:  existing.FullText,
+			Summary:   existing.Summary,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Article{}).Where("id = ?", existing.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[Feed] 文章内容已更新 [%s] (重新处理: %v)", incoming.Title, requeue)
+
+	// 内容变化后重新计算 SimHash,检查是否仍属于原来的事件
+	updated := *existing
+	updated.Title = incoming.Title
+	updated.Content = incoming.Content
+	updated.FullText = incoming.FullText
+	updated.FullHTML = incoming.FullHTML
+	if requeue {
+		updated.Status = model.StatusPending
+	}
+	if err := s.cluster.Reassign(&updated); err != nil {
+		log.Printf("[Feed] 文章重新聚类失败 [%s]: %v", incoming.Title, err)
+	}
+	return nil
+}
+
+// DiffOp 差异片段
+type DiffOp struct {
+	Type string `json:"type"` // equal, insert, delete
+	Text string `json:"text"`
+}
+
+// DiffText 按词比较两段文本 (中日韩文字按字),返回差异片段
+func DiffText(oldText, newText string) []DiffOp {
+	a := diffTokens(oldText)
+	b := diffTokens(newText)
+
+	// 公共前后缀不参与LCS计算
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []DiffOp
+	appendOp := func(typ, text string) {
+		if text == "" {
+			return
+		}
+		if n := len(ops); n > 0 && ops[n-1].Type == typ {
+			ops[n-1].Text += text
+			return
+		}
+		ops = append(ops, DiffOp{Type: typ, Text: text})
+	}
+
+	appendOp("equal", strings.Join(a[:prefix], ""))
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if len(midA) > diffMaxTokens || len(midB) > diffMaxTokens {
+		// 过长时不做细粒度比较
+		appendOp("delete", strings.Join(midA, ""))
+		appendOp("insert", strings.Join(midB, ""))
+	} else {
+		for _, op := range lcsDiff(midA, midB) {
+			appendOp(op.Type, op.Text)
+		}
+	}
+
+	appendOp("equal", strings.Join(a[len(a)-suffix:], ""))
+	return ops
+}
+
+func lcsDiff(a, b []string) []DiffOp {
+	// lengths[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lengths := make([][]int32, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var ops []DiffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, DiffOp{Type: "equal", Text: a[i]})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			ops = append(ops, DiffOp{Type: "delete", Text: a[i]})
+			i++
+		default:
+			ops = append(ops, DiffOp{Type: "insert", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, DiffOp{Type: "delete", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, DiffOp{Type: "insert", Text: b[j]})
+	}
+	return ops
+}
+
+// diffTokens 切分为单词、空白和单个中日韩字符,拼接后与原文一致
+func diffTokens(text string) []string {
+	var tokens []string
+	var current []rune
+	var currentSpace bool
+
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, string(current))
+			current = current[:0]
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.IsPunct(r):
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			space := unicode.IsSpace(r)
+			if len(current) > 0 && space != currentSpace {
+				flush()
+			}
+			currentSpace = space
+			current = append(current, r)
+		}
+	}
+	flush()
+	return tokens
+}
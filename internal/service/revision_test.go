@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-news/config"
+	"go-news/internal/model"
+)
+
+func TestApplyUpdateRequeue(t *testing.T) {
+	s := newTestFeedService(t, config.FetchConfig{})
+	s.db.Create(&model.Config{Key: model.ConfigReprocessOnUpdate, Value: "true"})
+	feed := model.Feed{Name: "test", URL: "https://example.com/feed"}
+	s.db.Create(&feed)
+
+	// 曾经失败重试过的文章,处理成功后内容又有更新
+	retryAt := time.Now().Add(time.Hour)
+	existing := model.Article{
+		FeedID: feed.ID, GUID: "a", Title: "Go 1.25 released", Content: "old", Summary: "摘要",
+		Status: model.StatusProcessed, Attempts: 3, LastError: "HTTP 500", NextRetryAt: &retryAt,
+	}
+	s.db.Create(&existing)
+
+	incoming := &model.Article{Title: existing.Title, Content: "new"}
+	if err := s.applyUpdate(context.Background(), &feed, &existing, incoming); err != nil {
+		t.Fatal(err)
+	}
+
+	var got model.Article
+	s.db.First(&got, existing.ID)
+	if got.Status != model.StatusPending || got.Attempts != 0 || got.LastError != "" || got.NextRetryAt != nil {
+		t.Errorf("重新排队后 status=%d attempts=%d last_error=%q next_retry_at=%v",
+			got.Status, got.Attempts, got.LastError, got.NextRetryAt)
+	}
+	if got.RevisionCount != 1 || got.Content != "new" {
+		t.Errorf("revision_count=%d content=%q", got.RevisionCount, got.Content)
+	}
+}
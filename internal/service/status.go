@@ -17,6 +17,8 @@ type SystemStatus struct {
 	PendingArticles   int64 `json:"pending_articles"`
 	ProcessedArticles int64 `json:"processed_articles"`
 	FilteredArticles  int64 `json:"filtered_articles"`
+	DuplicateArticles int64 `json:"duplicate_articles"`
+	FailedArticles    int64 `json:"failed_articles"`
 
 	// 订阅源统计
 	TotalFeeds   int64 `json:"total_feeds"`
@@ -25,6 +27,9 @@ type SystemStatus struct {
 	// 定时任务信息
 	NextFetchTime   time.Time `json:"next_fetch_time"`
 	NextProcessTime time.Time `json:"next_process_time"`
+
+	// 最近一次批量抓取
+	LastFetch *FetchSummary `json:"last_fetch,omitempty"`
 }
 
 func NewStatusService(db *gorm.DB) *StatusService {
@@ -40,6 +45,8 @@ func (s *StatusService) GetSystemStatus() (*SystemStatus, error) {
 	s.db.Model(&model.Article{}).Where("status = ?", model.StatusPending).Count(&status.PendingArticles)
 	s.db.Model(&model.Article{}).Where("status = ?", model.StatusProcessed).Count(&status.ProcessedArticles)
 	s.db.Model(&model.Article{}).Where("status = ?", model.StatusFiltered).Count(&status.FilteredArticles)
+	s.db.Model(&model.Article{}).Where("status = ?", model.StatusDuplicate).Count(&status.DuplicateArticles)
+	s.db.Model(&model.Article{}).Where("status = ?", model.StatusFailed).Count(&status.FailedArticles)
 
 	// 统计订阅源
 	s.db.Model(&model.Feed{}).Count(&status.TotalFeeds)
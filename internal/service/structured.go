@@ -0,0 +1,246 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"go-news/internal/model"
+)
+
+// JSONSchema 结构化输出使用的 JSON Schema,Name 供需要命名的提供商使用
+type JSONSchema struct {
+	Name   string
+	Schema map[string]interface{}
+}
+
+// filterSchema 筛选阶段的输出格式
+var filterSchema = &JSONSchema{
+	Name: "filter_result",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"worth":    map[string]interface{}{"type": "boolean", "description": "文章是否值得阅读"},
+			"reason":   map[string]interface{}{"type": "string", "description": "简短说明原因"},
+			"score":    map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 100, "description": "相关度,0-100"},
+			"category": map[string]interface{}{"type": "string", "description": "文章分类,一个简短的词语"},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "1到5个主题标签",
+			},
+		},
+		"required":             []string{"worth", "reason", "score", "category", "tags"},
+		"additionalProperties": false,
+	},
+}
+
+// combinedSchema 合并模式的输出格式: 筛选结果加摘要
+var combinedSchema = extendSchema(filterSchema, "process_result", map[string]interface{}{
+	"summary": map[string]interface{}{"type": "string", "description": "中文摘要,不值得阅读时为空"},
+})
+
+// entitySchema 实体提取阶段的输出格式
+var entitySchema = &JSONSchema{
+	Name: "entity_result",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"entities": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"type": map[string]interface{}{"type": "string", "enum": model.EntityTypes, "description": "实体类型"},
+						"name": map[string]interface{}{"type": "string", "description": "实体名称,使用原文中的写法"},
+					},
+					"required":             []string{"type", "name"},
+					"additionalProperties": false,
+				},
+				"description": "文章中提到的公司、产品、人物、漏洞编号、带版本号的产品和其他关键词",
+			},
+		},
+		"required":             []string{"entities"},
+		"additionalProperties": false,
+	},
+}
+
+// extendSchema 在对象 Schema 上追加必填字段,生成新的 Schema
+func extendSchema(base *JSONSchema, name string, extra map[string]interface{}) *JSONSchema {
+	schema := make(map[string]interface{}, len(base.Schema))
+	for key, v := range base.Schema {
+		schema[key] = v
+	}
+
+	properties := make(map[string]interface{})
+	for key, v := range base.Schema["properties"].(map[string]interface{}) {
+		properties[key] = v
+	}
+	required := append([]string{}, base.Schema["required"].([]string)...)
+	for key, v := range extra {
+		properties[key] = v
+		required = append(required, key)
+	}
+	schema["properties"] = properties
+	schema["required"] = required
+
+	return &JSONSchema{Name: name, Schema: schema}
+}
+
+// decodeStructured 去掉代码块标记后解析 JSON,按 Schema 校验后写入 out
+func decodeStructured(text string, schema *JSONSchema, out interface{}) error {
+	text = stripCodeFence(text)
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return fmt.Errorf("不是有效的JSON对象: %v", err)
+	}
+	if err := validateSchema(schema.Schema, raw); err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(text), out)
+}
+
+// stripCodeFence 去掉模型常加的 ```json ... ``` 包裹和前后说明文字
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if start := strings.Index(text, "```"); start >= 0 {
+		inner := text[start+3:]
+		// 跳过语言标记,如 ```json
+		if nl := strings.IndexByte(inner, '\n'); nl >= 0 {
+			inner = inner[nl+1:]
+		}
+		if end := strings.LastIndex(inner, "```"); end >= 0 {
+			inner = inner[:end]
+		}
+		text = strings.TrimSpace(inner)
+	}
+
+	// 只保留最外层的 JSON 对象
+	if start, end := strings.IndexByte(text, '{'), strings.LastIndexByte(text, '}'); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+	return text
+}
+
+// validateSchema 校验对象的必填字段、字段类型、枚举和数值范围,
+// 只覆盖本项目用到的 Schema 子集
+func validateSchema(schema map[string]interface{}, value map[string]interface{}) error {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	if required, ok := schema["required"].([]string); ok {
+		for _, key := range required {
+			if _, exists := value[key]; !exists {
+				return fmt.Errorf("缺少字段: %s", key)
+			}
+		}
+	}
+
+	for key, v := range value {
+		prop, ok := properties[key].(map[string]interface{})
+		if !ok {
+			if schema["additionalProperties"] == false {
+				return fmt.Errorf("未知字段: %s", key)
+			}
+			continue
+		}
+		if err := validateValue(key, prop, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateValue(key string, prop map[string]interface{}, v interface{}) error {
+	typ, _ := prop["type"].(string)
+	switch typ {
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("字段 %s 应为布尔值", key)
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("字段 %s 应为字符串", key)
+		}
+		if enum, ok := prop["enum"].([]string); ok && !containsString(enum, s) {
+			return fmt.Errorf("字段 %s 的值 %q 不在可选范围内", key, s)
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("字段 %s 应为数字", key)
+		}
+		if typ == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("字段 %s 应为整数", key)
+		}
+		if min, ok := prop["minimum"].(int); ok && n < float64(min) {
+			return fmt.Errorf("字段 %s 不能小于 %d", key, min)
+		}
+		if max, ok := prop["maximum"].(int); ok && n > float64(max) {
+			return fmt.Errorf("字段 %s 不能大于 %d", key, max)
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("字段 %s 应为对象", key)
+		}
+		return validateSchema(prop, obj)
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("字段 %s 应为数组", key)
+		}
+		if itemSchema, ok := prop["items"].(map[string]interface{}); ok {
+			for _, item := range items {
+				if err := validateValue(key, itemSchema, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// googleSchema 转换为 Gemini responseSchema 支持的格式:
+// 类型名大写,不支持 additionalProperties
+func googleSchema(schema map[string]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{}, len(schema))
+	for key, v := range schema {
+		switch key {
+		case "additionalProperties":
+			continue
+		case "type":
+			if s, ok := v.(string); ok {
+				v = strings.ToUpper(s)
+			}
+		case "properties":
+			if props, ok := v.(map[string]interface{}); ok {
+				convertedProps := make(map[string]interface{}, len(props))
+				for name, prop := range props {
+					if p, ok := prop.(map[string]interface{}); ok {
+						convertedProps[name] = googleSchema(p)
+					}
+				}
+				v = convertedProps
+			}
+		case "items":
+			if item, ok := v.(map[string]interface{}); ok {
+				v = googleSchema(item)
+			}
+		}
+		converted[key] = v
+	}
+	return converted
+}
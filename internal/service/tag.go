@@ -0,0 +1,87 @@
+package service
+
+import (
+	"strings"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 每篇文章最多保存的标签数
+const maxArticleTags = 5
+
+// TagCount 标签及其关联的文章数
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type TagService struct {
+	db *gorm.DB
+}
+
+func NewTagService(db *gorm.DB) *TagService {
+	return &TagService{db: db}
+}
+
+// Resolve 规范化标签名 (去空白、小写、去重) 并查找或创建对应的标签
+func (s *TagService) Resolve(names []string) ([]model.Tag, error) {
+	tags := make([]model.Tag, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] || len([]rune(name)) > 50 {
+			continue
+		}
+		seen[name] = true
+
+		tag, err := s.findOrCreate(name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+		if len(tags) >= maxArticleTags {
+			break
+		}
+	}
+	return tags, nil
+}
+
+// findOrCreate 查找或创建标签。多个 worker 并发处理时可能同时创建同名标签,
+// 插入冲突时忽略并重新查询,避免唯一索引错误导致整篇文章处理失败
+func (s *TagService) findOrCreate(name string) (model.Tag, error) {
+	var tag model.Tag
+	if err := s.db.Where(model.Tag{Name: name}).Limit(1).Find(&tag).Error; err != nil || tag.ID != 0 {
+		return tag, err
+	}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.Tag{Name: name}).Error; err != nil {
+		return tag, err
+	}
+	err := s.db.Where(model.Tag{Name: name}).First(&tag).Error
+	return tag, err
+}
+
+// Popular 已处理文章中最常用的标签
+func (s *TagService) Popular(limit int) ([]TagCount, error) {
+	var tags []TagCount
+	err := s.db.Table("tags").
+		Select("tags.name, COUNT(*) AS count").
+		Joins("JOIN article_tags ON article_tags.tag_id = tags.id").
+		Joins("JOIN articles ON articles.id = article_tags.article_id").
+		Where("articles.status = ?", model.StatusProcessed).
+		Group("tags.id").
+		Order("count DESC, tags.name").
+		Limit(limit).
+		Scan(&tags).Error
+	return tags, err
+}
+
+// WhereTag 限定查询带有指定标签的文章
+func WhereTag(query *gorm.DB, tag string) *gorm.DB {
+	return query.Where("articles.id IN (?)",
+		query.Session(&gorm.Session{NewDB: true}).Table("article_tags").
+			Select("article_tags.article_id").
+			Joins("JOIN tags ON tags.id = article_tags.tag_id").
+			Where("tags.name = ?", strings.ToLower(strings.TrimSpace(tag))))
+}
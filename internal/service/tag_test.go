@@ -0,0 +1,52 @@
+package service
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"go-news/internal/model"
+)
+
+func TestTagResolve(t *testing.T) {
+	s := NewTagService(newTestDB(t, &model.Tag{}))
+
+	tags, err := s.Resolve([]string{" AI ", "ai", "", "Security", "a", "b", "c", "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if want := []string{"ai", "security", "a", "b", "c"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Resolve() = %v, want %v", names, want)
+	}
+}
+
+func TestTagResolveConcurrent(t *testing.T) {
+	s := NewTagService(newTestDB(t, &model.Tag{}))
+	names := []string{"ai", "security", "open source"}
+
+	const workers = 8
+	results := make([][]model.Tag, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.Resolve(names)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < workers; i++ {
+		if errs[i] != nil {
+			t.Fatalf("worker %d: %v", i, errs[i])
+		}
+		if !reflect.DeepEqual(results[i], results[0]) || len(results[i]) != len(names) {
+			t.Errorf("worker %d: %+v, want %+v", i, results[i], results[0])
+		}
+	}
+}
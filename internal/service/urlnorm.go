@@ -0,0 +1,64 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+)
+
+// 常见跟踪参数,去重前从链接中移除
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"mc_cid": true, "mc_eid": true, "igshid": true, "_hsenc": true, "_hsmi": true,
+	"ref_src": true, "spm": true, "ncid": true, "cmpid": true, "__twitter_impression": true,
+}
+
+// CleanURL 移除跟踪参数和锚点,规范化域名大小写和默认端口,结果仍可直接访问
+func CleanURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(raw)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+
+	query := u.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			query.Del(key)
+		}
+	}
+	// url.Values.Encode 按键排序,保证参数顺序稳定
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// CanonicalURL 生成用于去重的规范化链接: 在 CleanURL 基础上统一为 https,
+// 去掉 www. 前缀和末尾斜杠
+func CanonicalURL(raw string) string {
+	cleaned := CleanURL(raw)
+	u, err := url.Parse(cleaned)
+	if err != nil || u.Host == "" {
+		return cleaned
+	}
+
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	u.Host = strings.TrimPrefix(u.Host, "www.")
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	return u.String()
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-news/internal/model"
+	"gorm.io/gorm"
+)
+
+// ErrBudgetExceeded 本月LLM费用超出预算
+var ErrBudgetExceeded = errors.New("本月LLM费用已超出预算,暂停处理")
+
+// 已提示过未配置价格的模型,每个模型只提示一次
+var unpricedWarned sync.Map
+
+// ModelPrice 模型价格,单位: 美元/百万token
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// UsagePoint 某一天或某个月的用量汇总
+type UsagePoint struct {
+	Period           string  `json:"period"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// UsageReport 用量报表
+type UsageReport struct {
+	Daily          []UsagePoint `json:"daily"`
+	Monthly        []UsagePoint `json:"monthly"`
+	ByStage        []UsagePoint `json:"by_stage"` // 本月按阶段汇总,Period 为阶段名
+	MonthCost      float64      `json:"month_cost"`
+	MonthlyBudget  float64      `json:"monthly_budget"`
+	BudgetExceeded bool         `json:"budget_exceeded"`
+	UnpricedModels []string     `json:"unpriced_models"` // 本月有调用但未配置价格的模型,费用按0计入预算
+}
+
+type UsageService struct {
+	db *gorm.DB
+}
+
+func NewUsageService(db *gorm.DB) *UsageService {
+	return &UsageService{db: db}
+}
+
+// Record 保存一次调用的用量,按当前价格计算费用
+func (s *UsageService) Record(cfg *LLMConfig, meta ChatMeta, usage TokenUsage, estimated bool) error {
+	record := model.LLMUsage{
+		Stage:            meta.Stage,
+		Provider:         cfg.Provider,
+		Model:            cfg.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Estimated:        estimated,
+	}
+	if meta.ArticleID > 0 {
+		record.ArticleID = &meta.ArticleID
+	}
+
+	prices := parsePrices(configValue(s.db, model.ConfigLLMPrices))
+	if price, ok := matchModel(prices, cfg.Model); ok {
+		record.Cost = (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1e6
+	} else if _, warned := unpricedWarned.LoadOrStore(strings.ToLower(cfg.Model), true); !warned {
+		log.Printf("[LLM] 模型 %s 未配置价格,费用按0记录,每月预算不会限制该模型的调用", cfg.Model)
+	}
+
+	return s.db.Create(&record).Error
+}
+
+// MonthCost 本月累计费用
+func (s *UsageService) MonthCost() float64 {
+	var cost float64
+	s.db.Model(&model.LLMUsage{}).
+		Where("created_at >= ?", monthStart(time.Now())).
+		Select("COALESCE(SUM(cost), 0)").
+		Scan(&cost)
+	return cost
+}
+
+// MonthlyBudget 每月预算,0为不限制
+func (s *UsageService) MonthlyBudget() float64 {
+	budget, err := strconv.ParseFloat(strings.TrimSpace(configValue(s.db, model.ConfigLLMMonthlyBudget)), 64)
+	if err != nil || budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+// CheckBudget 本月费用达到预算时返回 ErrBudgetExceeded。
+// 费用按 llm_prices 计算,未配置价格的模型费用为0,不受预算限制
+func (s *UsageService) CheckBudget() error {
+	budget := s.MonthlyBudget()
+	if budget > 0 && s.MonthCost() >= budget {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// Report 最近 days 天的每日用量和最近 months 个月的每月用量
+func (s *UsageService) Report(days, months int) (*UsageReport, error) {
+	now := time.Now()
+	report := &UsageReport{MonthlyBudget: s.MonthlyBudget()}
+
+	// created_at 以本地时间字符串保存,截取前缀即为本地日期/月份
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	daily, err := s.aggregate("substr(created_at, 1, 10)", today.AddDate(0, 0, -(days-1)))
+	if err != nil {
+		return nil, err
+	}
+	report.Daily = fillPeriods(daily, days, func(i int) string {
+		return today.AddDate(0, 0, i-(days-1)).Format("2006-01-02")
+	})
+
+	start := monthStart(now)
+	monthly, err := s.aggregate("substr(created_at, 1, 7)", start.AddDate(0, -(months-1), 0))
+	if err != nil {
+		return nil, err
+	}
+	report.Monthly = fillPeriods(monthly, months, func(i int) string {
+		return start.AddDate(0, i-(months-1), 0).Format("2006-01")
+	})
+
+	if report.ByStage, err = s.aggregate("stage", start); err != nil {
+		return nil, err
+	}
+
+	for _, p := range report.ByStage {
+		report.MonthCost += p.Cost
+	}
+	report.BudgetExceeded = report.MonthlyBudget > 0 && report.MonthCost >= report.MonthlyBudget
+
+	if report.UnpricedModels, err = s.unpricedModels(start); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// unpricedModels since 之后有调用、费用为0且当前仍未配置价格的模型
+func (s *UsageService) unpricedModels(since time.Time) ([]string, error) {
+	var models []string
+	err := s.db.Model(&model.LLMUsage{}).
+		Where("created_at >= ?", since).
+		Group("model").
+		Having("SUM(cost) = 0").
+		Order("model").
+		Pluck("model", &models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	prices := parsePrices(configValue(s.db, model.ConfigLLMPrices))
+	unpriced := make([]string, 0, len(models))
+	for _, name := range models {
+		if _, ok := matchModel(prices, name); !ok {
+			unpriced = append(unpriced, name)
+		}
+	}
+	return unpriced, nil
+}
+
+// aggregate 按 period 表达式分组汇总 since 之后的用量
+func (s *UsageService) aggregate(period string, since time.Time) ([]UsagePoint, error) {
+	var points []UsagePoint
+	err := s.db.Model(&model.LLMUsage{}).
+		Select(fmt.Sprintf("%s AS period, COUNT(*) AS calls, "+
+			"SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, "+
+			"SUM(cost) AS cost", period)).
+		Where("created_at >= ?", since).
+		Group("period").
+		Order("period").
+		Scan(&points).Error
+	return points, err
+}
+
+// fillPeriods 按顺序生成 n 个周期,没有调用的周期补零,便于绘图
+func fillPeriods(points []UsagePoint, n int, label func(i int) string) []UsagePoint {
+	byPeriod := make(map[string]UsagePoint, len(points))
+	for _, p := range points {
+		byPeriod[p.Period] = p
+	}
+
+	filled := make([]UsagePoint, n)
+	for i := 0; i < n; i++ {
+		period := label(i)
+		filled[i] = byPeriod[period]
+		filled[i].Period = period
+	}
+	return filled
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// parsePrices 解析价格配置,每行: 模型名 输入价格 输出价格,# 开头为注释
+func parsePrices(text string) map[string]ModelPrice {
+	prices := make(map[string]ModelPrice)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		input, err1 := strconv.ParseFloat(fields[1], 64)
+		output, err2 := strconv.ParseFloat(fields[2], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		prices[strings.ToLower(fields[0])] = ModelPrice{Input: input, Output: output}
+	}
+	return prices
+}
+
+// matchModel 按模型名查找配置: 优先完全匹配,否则使用最长的前缀匹配,
+// 这样 gpt-4o-mini 可以匹配 gpt-4o-mini-2024-07-18
+func matchModel[T any](items map[string]T, modelName string) (T, bool) {
+	modelName = strings.ToLower(modelName)
+	if item, ok := items[modelName]; ok {
+		return item, true
+	}
+
+	var best string
+	for name := range items {
+		if strings.HasPrefix(modelName, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		var zero T
+		return zero, false
+	}
+	return items[best], true
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"go-news/internal/model"
+)
+
+func TestReportUnpricedModels(t *testing.T) {
+	db := newTestDB(t, &model.LLMUsage{}, &model.Config{})
+	db.Create(&model.Config{Key: model.ConfigLLMPrices, Value: "gpt-4o-mini 0.15 0.60\nllama3 0 0"})
+	db.Create(&model.Config{Key: model.ConfigLLMMonthlyBudget, Value: "1"})
+	s := NewUsageService(db)
+
+	usage := TokenUsage{PromptTokens: 1000000, CompletionTokens: 1000000}
+	for _, name := range []string{"gpt-4o-mini-2024-07-18", "llama3", "deepseek-chat", "deepseek-chat"} {
+		if err := s.Record(&LLMConfig{Provider: "openai", Model: name}, ChatMeta{Stage: model.StageFilter}, usage, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := s.Report(7, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MonthCost != 0.75 || report.BudgetExceeded {
+		t.Errorf("month_cost=%v budget_exceeded=%v, want 0.75 false", report.MonthCost, report.BudgetExceeded)
+	}
+	// 价格为0的本地模型不算未配置
+	if want := []string{"deepseek-chat"}; !reflect.DeepEqual(report.UnpricedModels, want) {
+		t.Errorf("unpriced_models = %v, want %v", report.UnpricedModels, want)
+	}
+
+	// 补充价格后不再提示
+	db.Model(&model.Config{}).Where("key = ?", model.ConfigLLMPrices).
+		Update("value", "gpt-4o-mini 0.15 0.60\nllama3 0 0\ndeepseek 0.27 1.10")
+	if report, _ = s.Report(7, 3); len(report.UnpricedModels) != 0 {
+		t.Errorf("unpriced_models = %v, want 空", report.UnpricedModels)
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"html/template"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/sqlite"
@@ -32,14 +34,24 @@ func main() {
 	}
 
 	// 自动迁移
-	db.AutoMigrate(&model.Feed{}, &model.Article{}, &model.Config{})
+	db.AutoMigrate(&model.Feed{}, &model.Article{}, &model.Config{}, &model.Story{}, &model.ArticleRevision{}, &model.LLMUsage{}, &model.LLMCall{}, &model.Tag{}, &model.FolderPrompt{},
+		&model.PromptVersion{}, &model.PromptExperiment{}, &model.PromptExperimentResult{},
+		&model.GoldenLabel{}, &model.Evaluation{}, &model.EvaluationItem{}, &model.ArticleVote{}, &model.Entity{})
 
 	// 初始化默认配置
 	initDefaultConfig(db)
 
+	// 子命令: go-news eval 评估黄金集
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		os.Exit(runEval(db, os.Args[2:]))
+	}
+
 	// 初始化服务
 	llmSvc := service.NewLLMService(db)
-	feedSvc := service.NewFeedService(db)
+	feedSvc := service.NewFeedService(db, cfg.Fetch)
+	if err := feedSvc.BackfillCanonicalURLs(); err != nil {
+		log.Println("Failed to backfill canonical URLs:", err)
+	}
 	processorSvc := service.NewProcessorService(db, llmSvc)
 
 	// 启动定时任务
@@ -55,7 +67,7 @@ func main() {
 	r.Static("/static", "web/static")
 
 	// 注册路由
-	h := handler.NewHandler(db)
+	h := handler.NewHandler(db, feedSvc, llmSvc, processorSvc)
 	h.SetScheduler(sched)
 	h.RegisterRoutes(r)
 
@@ -72,15 +84,99 @@ func initDefaultConfig(db *gorm.DB) {
 		model.ConfigLLMApiURL:   "https://api.openai.com/v1",
 		model.ConfigLLMModel:    "gpt-4o-mini",
 		model.ConfigPromptFilter: `你是一个新闻筛选助手。请判断以下文章是否值得阅读。
-返回JSON格式:{"worth": true/false, "reason": "简短说明原因"}
-只有重要的科技新闻、行业动态才值得阅读,广告、招聘信息、无意义内容不值得。`,
-		model.ConfigPromptSummary: `请用中文总结以下文章的核心内容,要求:
+返回JSON格式:{"worth": true/false, "reason": "简短说明原因", "score": 0-100的相关度, "category": "分类", "tags": ["标签"]}
+只有重要的科技新闻、行业动态才值得阅读,广告、招聘信息、无意义内容不值得。
+分类使用一个简短的中文词语 (如 人工智能、安全、开源、硬件、行业);标签为1到5个主题关键词。`,
+		model.ConfigPromptSummary: `请用{{.TargetLanguage}}总结以下文章的核心内容,要求:
 1. 控制在200字以内
 2. 突出关键信息
 3. 语言简洁易懂`,
+		model.ConfigPromptCombined: `你是一个新闻筛选和摘要助手。请判断以下文章是否值得阅读,值得阅读时同时生成摘要。
+返回JSON格式:{"worth": true/false, "reason": "简短说明原因", "score": 0-100的相关度, "category": "分类", "tags": ["标签"], "summary": "摘要"}
+只有重要的科技新闻、行业动态才值得阅读,广告、招聘信息、无意义内容不值得。
+分类使用一个简短的中文词语 (如 人工智能、安全、开源、硬件、行业);标签为1到5个主题关键词。
+摘要用{{.TargetLanguage}},控制在200字以内,突出关键信息,语言简洁易懂;不值得阅读时摘要留空。`,
+		model.ConfigPromptChunkSummary: `以下是一篇长文章的其中一部分。请用{{.TargetLanguage}}概括这一部分的要点:
+1. 保留关键事实、数据和结论
+2. 控制在150字以内
+3. 只输出要点,不要评论`,
+		model.ConfigPromptTranslate: `请将以下新闻标题翻译为{{.TargetLanguage}}。
+只输出译文,不要解释,不要加引号;专有名词、产品名和版本号保持原文。`,
+		model.ConfigPromptEntities: `请提取以下文章中提到的命名实体,返回JSON格式:{"entities": [{"type": "类型", "name": "名称"}]}
+类型只能是: company (公司和组织)、product (产品、项目和服务)、person (人物)、cve (漏洞编号,如 CVE-2024-3094)、version (带版本号的产品,如 Go 1.25)、keyword (其他重要的技术关键词)。
+名称使用原文中的常用写法,同一实体只列出一次,最多20个,只保留文章重点提到的实体。`,
+		model.ConfigReprocessOnUpdate:    "false",
+		model.ConfigScoreThreshold:       "0",
+		model.ConfigFewShotCount:         "6",
+		model.ConfigTargetLanguage:       "zh",
+		model.ConfigTranslateTitle:       "false",
+		model.ConfigEntityExtraction:     model.EntityExtractionRules,
+		model.ConfigProcessMode:          model.ProcessModeTwoStep,
+		model.ConfigLLMTimeout:           "120",
+		model.ConfigLLMMaxRetries:        "3",
+		model.ConfigLLMRequestsPerMinute: "0",
+		model.ConfigLLMTokensPerMinute:   "0",
+		model.ConfigLLMStructuredOutput:  "true",
+		model.ConfigLLMPrices: `# 模型名 输入价格 输出价格 (美元/百万token),按最长前缀匹配
+gpt-4o-mini 0.15 0.60
+gpt-4o 2.50 10.00
+gemini-2.0-flash 0.10 0.40
+claude-3-5-haiku 0.80 4.00`,
+		model.ConfigLLMModelLimits: `# 模型名 上下文窗口(token) [每token字符数,默认4],按最长前缀匹配,未列出的模型不截断
+gpt-4o 128000
+gemini-2.0-flash 1000000
+claude-3-5-haiku 200000
+llama3 8192 3.5
+qwen2.5 32768`,
+		model.ConfigFilterTruncation:     model.TruncateHead,
+		model.ConfigLLMMonthlyBudget:     "0", // 费用按 llm_prices 计算,未配置价格的模型按0计入,不受预算限制
+		model.ConfigLLMCallRetentionDays: "30",
+		model.ConfigLLMCallMaxRecords:    "10000",
 	}
 
 	for key, value := range defaults {
 		db.Where("key = ?", key).FirstOrCreate(&model.Config{Key: key, Value: value})
 	}
+	migrateDefaultPrompts(db, defaults)
+}
+
+// legacyPrompts 旧版本的默认提示词。FirstOrCreate 不会更新已有配置,
+// 升级前保存的默认提示词缺少新增的输出字段或模板变量,未修改过的在启动时替换为当前默认值
+var legacyPrompts = map[string][]string{
+	// 筛选结果增加 score、category、tags 之前
+	model.ConfigPromptFilter: {`你是一个新闻筛选助手。请判断以下文章是否值得阅读。
+返回JSON格式:{"worth": true/false, "reason": "简短说明原因"}
+只有重要的科技新闻、行业动态才值得阅读,广告、招聘信息、无意义内容不值得。`},
+	// 以下为使用 {{.TargetLanguage}} 之前固定输出中文的版本
+	model.ConfigPromptSummary: {`请用中文总结以下文章的核心内容,要求:
+1. 控制在200字以内
+2. 突出关键信息
+3. 语言简洁易懂`},
+	model.ConfigPromptCombined: {`你是一个新闻筛选和摘要助手。请判断以下文章是否值得阅读,值得阅读时同时生成摘要。
+返回JSON格式:{"worth": true/false, "reason": "简短说明原因", "score": 0-100的相关度, "category": "分类", "tags": ["标签"], "summary": "摘要"}
+只有重要的科技新闻、行业动态才值得阅读,广告、招聘信息、无意义内容不值得。
+分类使用一个简短的中文词语 (如 人工智能、安全、开源、硬件、行业);标签为1到5个主题关键词。
+摘要用中文,控制在200字以内,突出关键信息,语言简洁易懂;不值得阅读时摘要留空。`},
+	model.ConfigPromptChunkSummary: {`以下是一篇长文章的其中一部分。请用中文概括这一部分的要点:
+1. 保留关键事实、数据和结论
+2. 控制在150字以内
+3. 只输出要点,不要评论`},
+}
+
+// migrateDefaultPrompts 将与旧默认值完全相同的提示词更新为当前默认值,用户修改过的提示词保持不变
+func migrateDefaultPrompts(db *gorm.DB, defaults map[string]string) {
+	for key, olds := range legacyPrompts {
+		var existing model.Config
+		if err := db.Where("key = ?", key).First(&existing).Error; err != nil {
+			continue
+		}
+		current := strings.TrimSpace(strings.ReplaceAll(existing.Value, "\r\n", "\n"))
+		for _, old := range olds {
+			if current == old {
+				db.Model(&existing).Update("value", defaults[key])
+				log.Printf("默认提示词 %s 已更新为新版本", key)
+				break
+			}
+		}
+	}
 }